@@ -1,14 +1,19 @@
 package main
 
 import (
+	"bufio"
+	"context"
 	"crypto/tls"
 	"crypto/x509"
 	"flag"
 	"fmt"
 	"io"
 	"log"
+	"log/slog"
+	"maps"
 	"nsd/pkg/client"
 	"os"
+	"slices"
 	"strings"
 )
 
@@ -28,10 +33,12 @@ func (s SimpleAddr) String() string {
 }
 
 func main() {
+	configPath := flag.String("c", "", "nsd.conf to read the remote-control settings from, overrides the other flags")
 	connUrl := flag.String("i", defaultSocket, "server address and port, or socket path")
 	caPath := flag.String("ca", "", "Server CA certificate path")
 	clientCertPath := flag.String("client-cert", "", "Client certificate path")
 	clientKeyPath := flag.String("client-key", "", "Client private key path")
+	verbose := flag.Bool("v", false, "Log the commands and replies to stderr, with secrets redacted")
 	flag.Parse()
 	posArgs := flag.Args()
 
@@ -42,11 +49,14 @@ func main() {
 	}
 
 	var c *client.Client
-	if _, err := os.Stat(*connUrl); err == nil {
-		c, err = client.NewUNIXSocketClient(*connUrl)
-		if err != nil {
+	if *configPath != "" {
+		var err error
+		if c, err = client.NewClientFromConfig(*configPath); err != nil {
 			log.Fatal(err)
 		}
+	} else if _, err := os.Stat(*connUrl); err == nil {
+		// Dialing clients reconnect for every command, as commands on several zones send one per zone
+		c = client.NewDialingUNIXSocketClient(*connUrl)
 	} else {
 		// CA that signed the server certificate
 		if caPath == nil || *caPath == "" {
@@ -76,16 +86,16 @@ func main() {
 			return
 		}
 
-		c, err = client.NewSimpleTLSClient(SimpleAddr(*connUrl), caPool, clientCert)
-		if err != nil {
-			log.Fatal(err)
-			return
-		}
+		c = client.NewDialingSimpleTLSClient(SimpleAddr(*connUrl), caPool, clientCert)
 	}
 
 	defer mustClose(c)
 
-	doCommand(c, posArgs[0])
+	if *verbose {
+		c.SetLogger(slog.New(slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{Level: slog.LevelDebug})))
+	}
+
+	doCommand(c, posArgs[0], posArgs[1:])
 }
 
 func mustClose(c io.Closer) {
@@ -95,27 +105,52 @@ func mustClose(c io.Closer) {
 	}
 }
 
-func doCommand(c *client.Client, cmd string) {
+func doCommand(c *client.Client, cmd string, args []string) {
 	switch cmd {
 	case "stop":
 		if err := c.Stop(); err != nil {
 			log.Fatal(err)
 		}
+	case "reload", "write", "notify", "transfer", "force_transfer":
+		op := map[string]func([]string) (*client.ZoneOperationResult, error){
+			"reload":         c.Reload,
+			"write":          c.Write,
+			"notify":         c.Notify,
+			"transfer":       c.Transfer,
+			"force_transfer": c.ForceTransfer,
+		}[cmd]
+		if _, err := op(args); err != nil {
+			log.Fatal(err)
+		}
+		fmt.Println("ok")
+	case "reconfig", "repattern":
+		if report, err := c.Repattern(); err != nil {
+			if report != nil {
+				for _, l := range report.Errors {
+					_, _ = fmt.Fprintln(os.Stderr, l)
+				}
+			}
+			log.Fatal(err)
+		}
+		fmt.Println("ok")
 	case "status":
-		if lines, err := c.Status(); err != nil {
+		if status, err := c.Status(); err != nil {
 			log.Fatal(err)
 		} else {
-			for _, l := range lines {
-				fmt.Printf("%s\n", l)
+			fmt.Printf("version: %s\n", status.Version)
+			fmt.Printf("verbosity: %d\n", status.Verbosity)
+			if status.Ratelimit != nil {
+				fmt.Printf("ratelimit: %d\n", *status.Ratelimit)
+			}
+			for _, key := range slices.Sorted(maps.Keys(status.Other)) {
+				fmt.Printf("%s: %s\n", key, status.Other[key])
 			}
 		}
 	case "stats":
-		if lines, err := c.Stats(); err != nil {
+		if stats, err := c.Stats(); err != nil {
 			log.Fatal(err)
 		} else {
-			for _, l := range lines {
-				fmt.Printf("%s\n", l)
-			}
+			printStats(stats)
 		}
 	case "serverpid":
 		if pid, err := c.ServerPID(); err != nil {
@@ -123,6 +158,49 @@ func doCommand(c *client.Client, cmd string) {
 		} else {
 			fmt.Println(pid)
 		}
+	case "addzones":
+		var zones []client.ZonePattern
+		for _, line := range readStdinLines() {
+			zone, pattern, _ := strings.Cut(line, " ")
+			zones = append(zones, client.ZonePattern{Zone: zone, Pattern: strings.TrimSpace(pattern)})
+		}
+		printBulkResult(c.AddZones(zones))
+	case "delzones":
+		printBulkResult(c.DelZones(readStdinLines()))
+	case "zonestatus":
+		var statuses []client.ZoneStatus
+		if len(args) > 0 {
+			status, err := c.ZoneStatus(args[0])
+			if err != nil {
+				log.Fatal(err)
+			}
+			statuses = append(statuses, *status)
+		} else {
+			var err error
+			if statuses, err = c.ZoneStatuses(); err != nil {
+				log.Fatal(err)
+			}
+		}
+		for _, status := range statuses {
+			printZoneStatus(status)
+		}
+	case "print_tsig":
+		var keys []client.TsigKey
+		if len(args) > 0 {
+			key, err := c.GetTSig(args[0])
+			if err != nil {
+				log.Fatal(err)
+			}
+			keys = append(keys, *key)
+		} else {
+			var err error
+			if keys, err = c.GetTSigs(); err != nil {
+				log.Fatal(err)
+			}
+		}
+		for _, key := range keys {
+			fmt.Printf("key: name: \"%s\" secret: \"%s\" algorithm: %s\n", key.Name, key.Secret, key.Algorithm)
+		}
 	case "print_cookie_secrets":
 		if cookieSecrets, err := c.GetCookieSecrets(); err != nil {
 			log.Fatal(err)
@@ -133,5 +211,116 @@ func doCommand(c *client.Client, cmd string) {
 				fmt.Printf("staging: %v\n", *cookieSecrets.Staging)
 			}
 		}
+	default:
+		// Commands not wrapped by the client are sent as is
+		reply, err := c.Do(context.Background(), cmd, args...)
+		if reply != nil {
+			for _, line := range reply.Lines {
+				fmt.Println(line)
+			}
+		}
+		if err != nil {
+			log.Fatal(err)
+		}
+	}
+}
+
+func readStdinLines() []string {
+	var lines []string
+	scanner := bufio.NewScanner(os.Stdin)
+	for scanner.Scan() {
+		if line := strings.TrimSpace(scanner.Text()); line != "" {
+			lines = append(lines, line)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		log.Fatal(err)
+	}
+	return lines
+}
+
+func printBulkResult(result *client.ZoneOperationResult, err error) {
+	if result != nil {
+		for zone, msg := range result.Failed {
+			fmt.Printf("%s: %s\n", zone, msg)
+		}
+		fmt.Printf("ok: %d, failed: %d\n", len(result.Succeeded), len(result.Failed))
+	}
+	if err != nil {
+		log.Fatal(err)
+	}
+}
+
+func printZoneStatus(status client.ZoneStatus) {
+	fmt.Printf("zone:\t%s\n", status.Zone)
+	if status.Pattern != "" {
+		fmt.Printf("\tpattern: %s\n", status.Pattern)
+	}
+	if status.Catalog != "" {
+		fmt.Printf("\tcatalog: %s\n", status.Catalog)
+	}
+	if status.CatalogMemberID != "" {
+		fmt.Printf("\tcatalog-member-id: %s\n", status.CatalogMemberID)
+	}
+	fmt.Printf("\tstate: %s\n", status.State)
+	// Serials are only reported for secondary zones
+	for _, serial := range []struct {
+		name   string
+		serial *client.ZoneSerial
+	}{
+		{"served-serial", status.ServedSerial},
+		{"commit-serial", status.CommitSerial},
+	} {
+		if serial.serial != nil {
+			fmt.Printf("\t%s: %d since %s\n", serial.name, serial.serial.Serial, serial.serial.Since.Format("2006-01-02T15:04:05"))
+		}
+	}
+	if status.Wait != nil {
+		fmt.Printf("\twait: %s %s\n", status.Wait.Duration, status.Wait.Reason)
+	}
+	if status.Transfer != nil {
+		fmt.Printf("\ttransfer: %s %s\n", status.Transfer.Status, status.Transfer.Address)
+	}
+	for _, key := range slices.Sorted(maps.Keys(status.Attributes)) {
+		fmt.Printf("\t%s: %s\n", key, status.Attributes[key])
+	}
+}
+
+func printStats(stats *client.Stats) {
+	for i, queries := range stats.ServerQueries {
+		fmt.Printf("server%d.queries=%d\n", i, queries)
+	}
+	fmt.Printf("num.queries=%d\n", stats.Queries)
+	fmt.Printf("time.boot=%.6f\n", stats.TimeBoot.Seconds())
+	fmt.Printf("time.elapsed=%.6f\n", stats.TimeElapsed.Seconds())
+	fmt.Printf("size.db.disk=%d\n", stats.Memory.DBDisk)
+	fmt.Printf("size.db.mem=%d\n", stats.Memory.DBMem)
+	fmt.Printf("size.xfrd.mem=%d\n", stats.Memory.XfrdMem)
+	fmt.Printf("size.config.disk=%d\n", stats.Memory.ConfigDisk)
+	fmt.Printf("size.config.mem=%d\n", stats.Memory.ConfigMem)
+	for _, counters := range []struct {
+		prefix string
+		values map[string]uint64
+	}{
+		{"num.type.", stats.QueryTypes},
+		{"num.opcode.", stats.Opcodes},
+		{"num.class.", stats.Classes},
+		{"num.rcode.", stats.Rcodes},
+	} {
+		for _, name := range slices.Sorted(maps.Keys(counters.values)) {
+			fmt.Printf("%s%s=%d\n", counters.prefix, name, counters.values[name])
+		}
+	}
+	fmt.Printf("num.udp=%d\nnum.udp6=%d\n", stats.Transport.UDP, stats.Transport.UDP6)
+	fmt.Printf("num.tcp=%d\nnum.tcp6=%d\n", stats.Transport.TCP, stats.Transport.TCP6)
+	fmt.Printf("num.tls=%d\nnum.tls6=%d\n", stats.Transport.TLS, stats.Transport.TLS6)
+	fmt.Printf("num.edns=%d\nnum.ednserr=%d\n", stats.EDNS, stats.EDNSErr)
+	fmt.Printf("num.answer_wo_aa=%d\n", stats.AnswerWithoutAA)
+	fmt.Printf("num.rxerr=%d\nnum.txerr=%d\n", stats.RxErr, stats.TxErr)
+	fmt.Printf("num.raxfr=%d\nnum.rixfr=%d\n", stats.RAXFR, stats.RIXFR)
+	fmt.Printf("num.truncated=%d\nnum.dropped=%d\n", stats.Truncated, stats.Dropped)
+	fmt.Printf("zone.primary=%d\nzone.secondary=%d\n", stats.Zones.Primary, stats.Zones.Secondary)
+	for _, key := range slices.Sorted(maps.Keys(stats.Other)) {
+		fmt.Printf("%s=%s\n", key, stats.Other[key])
 	}
 }
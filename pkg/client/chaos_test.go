@@ -0,0 +1,188 @@
+package client_test
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"io"
+	"slices"
+	"syscall"
+	"testing"
+	"time"
+
+	"nsd/pkg/client"
+	"nsd/pkg/client/nsdtest"
+)
+
+// chaosCommands runs a command of every kind of reply, the bulk ones read the reply while sending.
+// validWith lists the faults that can leave a valid reply for the command.
+var chaosCommands = []struct {
+	name      string
+	validWith []nsdtest.Fault
+	run       func(ctx context.Context, c *client.Client) error
+}{
+	// Unknown keys of status are kept in Other
+	{"status", []nsdtest.Fault{nsdtest.FaultGarbage}, func(ctx context.Context, c *client.Client) error {
+		_, err := c.StatusContext(ctx)
+		return err
+	}},
+	{"serverpid", nil, func(ctx context.Context, c *client.Client) error {
+		_, err := c.ServerPIDContext(ctx)
+		return err
+	}},
+	{"stats_noreset", nil, func(ctx context.Context, c *client.Client) error {
+		_, err := c.StatsNoResetContext(ctx)
+		return err
+	}},
+	{"zonestatus", nil, func(ctx context.Context, c *client.Client) error {
+		_, err := c.ZoneStatusesContext(ctx)
+		return err
+	}},
+	{"zonestatus stream", nil, func(ctx context.Context, c *client.Client) error {
+		for _, err := range c.ZoneStatusesSeq(ctx) {
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	}},
+	{"print_tsig", nil, func(ctx context.Context, c *client.Client) error {
+		_, err := c.GetTSigsContext(ctx)
+		return err
+	}},
+	// Cookie secrets are not validated
+	{"print_cookie_secrets", []nsdtest.Fault{nsdtest.FaultGarbage}, func(ctx context.Context, c *client.Client) error {
+		_, err := c.GetCookieSecretsContext(ctx)
+		return err
+	}},
+	{"addzones", nil, func(ctx context.Context, c *client.Client) error {
+		_, err := c.AddZonesContext(ctx, []client.ZonePattern{{Zone: "chaos.example", Pattern: "replica"}})
+		if errors.Is(err, client.ErrZoneExists) {
+			// Added by an earlier session whose reply was lost
+			return nil
+		}
+		return err
+	}},
+	{"delzones", nil, func(ctx context.Context, c *client.Client) error {
+		_, err := c.DelZonesContext(ctx, []string{"chaos.example"})
+		if errors.Is(err, client.ErrZoneNotFound) {
+			return nil
+		}
+		return err
+	}},
+}
+
+// cleanError reports whether err is one of the errors the client returns for misbehaving servers and networks
+func cleanError(err error) bool {
+	var serverErr *client.ServerError
+	var protocolErr *client.ProtocolError
+	return errors.As(err, &serverErr) || errors.As(err, &protocolErr) || client.IsConnectionError(err) ||
+		errors.Is(err, bufio.ErrTooLong)
+}
+
+func TestClient_chaos(t *testing.T) {
+	server := nsdtest.NewUNIXServer(nil)
+	defer server.Close()
+
+	tests := []struct {
+		name    string
+		chaos   nsdtest.Chaos
+		fault   nsdtest.Fault
+		wantErr func(err error) bool
+	}{
+		{
+			name:    "latency",
+			chaos:   nsdtest.Chaos{Latency: time.Millisecond},
+			fault:   nsdtest.FaultNone,
+			wantErr: func(err error) bool { return err == nil },
+		},
+		{
+			name:    "disconnect",
+			chaos:   nsdtest.Chaos{Disconnect: 1},
+			fault:   nsdtest.FaultDisconnect,
+			wantErr: func(err error) bool { return errors.Is(err, syscall.ECONNRESET) },
+		},
+		{
+			name:    "truncate",
+			chaos:   nsdtest.Chaos{Truncate: 1},
+			fault:   nsdtest.FaultTruncate,
+			wantErr: func(err error) bool { return errors.Is(err, io.ErrUnexpectedEOF) },
+		},
+		{
+			name:    "unterminated",
+			chaos:   nsdtest.Chaos{Unterminated: 1},
+			fault:   nsdtest.FaultUnterminated,
+			wantErr: func(err error) bool { return errors.Is(err, context.DeadlineExceeded) },
+		},
+		{
+			name:    "oversized",
+			chaos:   nsdtest.Chaos{Oversized: 1},
+			fault:   nsdtest.FaultOversized,
+			wantErr: func(err error) bool { return errors.Is(err, bufio.ErrTooLong) },
+		},
+		{
+			name:    "garbage",
+			chaos:   nsdtest.Chaos{Garbage: 1},
+			fault:   nsdtest.FaultGarbage,
+			wantErr: func(err error) bool { return err != nil && cleanError(err) },
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.chaos.Seed = 1
+			c := client.New(nsdtest.NewChaosDialer(server.Dialer(), tt.chaos))
+			defer func() { _ = c.Close() }()
+			for _, command := range chaosCommands {
+				ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+				err := command.run(ctx, c)
+				cancel()
+				if err == nil && slices.Contains(command.validWith, tt.fault) {
+					continue
+				}
+				if !tt.wantErr(err) {
+					t.Errorf("%s error = %v", command.name, err)
+				}
+			}
+		})
+	}
+}
+
+func TestClient_chaosRetry(t *testing.T) {
+	server := nsdtest.NewUNIXServer(nil)
+	defer server.Close()
+
+	// Disconnected and truncated replies are connection errors, so idempotent commands succeed when retried often enough.
+	// Unterminated replies are left out, a command that timed out is not retried.
+	dialer := nsdtest.NewChaosDialer(server.Dialer(), nsdtest.Chaos{
+		Seed:       7,
+		Latency:    time.Millisecond,
+		Disconnect: 0.15,
+		Truncate:   0.15,
+		Oversized:  0.15,
+	})
+	policy := client.DefaultRetryPolicy
+	policy.MaxAttempts = 20
+	policy.InitialBackoff = time.Millisecond
+	policy.MaxBackoff = 5 * time.Millisecond
+	policy.Retryable = func(err error) bool {
+		return client.IsConnectionError(err) || errors.Is(err, bufio.ErrTooLong)
+	}
+	c := client.New(dialer, client.WithRetryPolicy(&policy), client.WithTimeout(100*time.Millisecond))
+	defer func() { _ = c.Close() }()
+
+	for range 10 {
+		for _, command := range chaosCommands {
+			switch command.name {
+			case "zonestatus stream", "addzones", "delzones":
+				// Streams and commands that are not idempotent are never retried
+				continue
+			}
+			if err := command.run(context.Background(), c); err != nil {
+				t.Errorf("%s error = %v", command.name, err)
+			}
+		}
+	}
+	if faults := dialer.Faults(); len(faults) <= 50 {
+		t.Errorf("%d connections, expected retries", len(faults))
+	}
+}
@@ -2,35 +2,86 @@ package client
 
 import (
 	"bufio"
+	"bytes"
+	"context"
 	"fmt"
 	"io"
+	"iter"
+	"log/slog"
+	"maps"
 	"regexp"
+	"slices"
 	"strconv"
 	"strings"
+	"sync"
+	"time"
 )
 
 // Transport agnostic Client for the NSD server's control socket.
 // Client is a transport agnostic client for the NSD server's control socket.
-// This is *not* thread-safe, it's the consumers responsibility to protect the Client from concurrent use.
+// Client is safe for concurrent use, commands on a client with a single connection are run one at a time.
+//
+// NSD closes the control connection after a single command.
+// Clients created by New and the NewDialing* and NewPooled* constructors use a new connection for every command and can be used repeatedly,
+// other clients can only be used for a single command.
 type Client struct {
 	// Server-side command parsing logic: https://github.com/NLnetLabs/nsd/blob/149049ca0a8e5536d2cfe60461b9f74d4f8ccc02/remote.c#L2606
 
 	socket  io.ReadWriteCloser
 	scanner *bufio.Scanner
+
+	// dial opens the connection for each command, nil if the client has a single connection in socket
+	dial dialFunc
+	// pool provides the connection for each command, nil if the client is not pooled
+	pool *connPool
+	// done is set on the per-command clients of dialing and pooled clients, and called once the command is done
+	done func()
+	// mu serializes commands on clients with a single connection
+	mu sync.Mutex
+	// retry is the retry policy for idempotent commands, nil if disabled
+	retry *RetryPolicy
+	// logger traces commands, nil disables logging
+	logger *slog.Logger
+	// observer is notified of completed commands, nil if disabled
+	observer CommandObserver
+	// traceCmd is the redacted command line being traced, see traceSend
+	traceCmd string
+	// traceStart is the start of the command being traced
+	traceStart time.Time
+
+	// timeout is the deadline applied to commands without a context deadline, zero disables it
+	timeout time.Duration
+	// stopInterrupt unregisters the cancellation of the current command, see begin
+	stopInterrupt func() bool
+	// interrupted is closed once a cancelled command has been interrupted
+	interrupted chan struct{}
 }
 
+// dialFunc opens a new connection to the server
+type dialFunc func(ctx context.Context) (io.ReadWriteCloser, error)
+
 type replyReader interface {
 	readReply() ([]string, error)
 }
 
 func (c *Client) init() (err error) {
 	c.scanner = bufio.NewScanner(c.socket)
-	c.scanner.Split(bufio.ScanLines)
+	c.scanner.Split(scanReplyLines)
 	_, err = c.socket.Write([]byte(headerVersion))
 	return
 }
 
+// scanReplyLines is bufio.ScanLines, except that a last line without newline is an error.
+// NSD ends every line with a newline, so the reply was cut short by the connection closing.
+func scanReplyLines(data []byte, atEOF bool) (int, []byte, error) {
+	if atEOF && len(data) > 0 && bytes.IndexByte(data, '\n') < 0 {
+		return 0, nil, io.ErrUnexpectedEOF
+	}
+	return bufio.ScanLines(data, atEOF)
+}
+
 func (c *Client) sendCmd(line string) error {
+	c.traceSend(line)
 	_, err := c.socket.Write([]byte(line + "\n"))
 	return err
 }
@@ -39,7 +90,9 @@ func (c *Client) readLine() (string, error) {
 	if !c.scanner.Scan() {
 		return "", c.scanner.Err()
 	}
-	return c.scanner.Text(), nil
+	line := c.scanner.Text()
+	c.traceReceive(line)
+	return line, nil
 }
 
 func (c *Client) readReply() ([]string, error) {
@@ -61,24 +114,42 @@ func expectOk(c replyReader) error {
 	if reply, err := c.readReply(); err != nil {
 		return err
 	} else if len(reply) != 1 {
-		return fmt.Errorf("unexpected reply: %s", reply)
+		return unexpectedReply(reply...)
 	} else if len(reply) == 1 {
 		if reply[0] == replyOK {
 			return nil
 		} else {
-			return fmt.Errorf("server send error: %s", reply[0])
+			return newServerError(reply[0])
 		}
 	} else {
-		return fmt.Errorf("unexpected reply: %+v", reply)
+		return unexpectedReply(reply...)
 	}
 }
 
 func (c *Client) Close() error {
+	if c.pool != nil {
+		return c.pool.close()
+	}
+	if c.socket == nil {
+		// Dialing clients have no connection between commands
+		return nil
+	}
 	return c.socket.Close()
 }
 
 // Stop request that NSD daemon stops
 func (c *Client) Stop() error {
+	return c.StopContext(context.Background())
+}
+
+// StopContext is like Stop but uses ctx for the deadline and cancellation of the command
+func (c *Client) StopContext(ctx context.Context) (err error) {
+	c, err = c.begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer c.end(ctx, &err)
+
 	// NSD handler: https://github.com/NLnetLabs/nsd/blob/NSD_4_11_0_REL/remote.c#L881
 	if err := c.sendCmd(cmdStop); err != nil {
 		return err
@@ -87,23 +158,196 @@ func (c *Client) Stop() error {
 	return expectOk(c)
 }
 
-// Reload causes NSD to reload modified zone files from disk
-func (c *Client) Reload(zones []string) {
+// Reload causes NSD to reload modified zone files from disk.
+// If no zones are given all zones are reloaded.
+// The returned result is populated even if an error is returned for zones that failed to reload.
+// Each zone is sent in its own command, so several zones need a dialing or pooled client, see ErrSingleConnection.
+func (c *Client) Reload(zones []string) (*ZoneOperationResult, error) {
+	return c.ReloadContext(context.Background(), zones)
+}
+
+// ReloadContext is like Reload but uses ctx for the deadline and cancellation of the command
+func (c *Client) ReloadContext(ctx context.Context, zones []string) (*ZoneOperationResult, error) {
 	// NSD handler: https://github.com/NLnetLabs/nsd/blob/NSD_4_11_0_REL/remote.c#L902
-	//TODO implement me
-	panic("implement me")
+	return c.zoneOperation(ctx, cmdReload, zones)
+}
+
+// zoneOperation runs cmd once for all zones, or once per zone as NSD accepts a single zone argument.
+// The results of the zones are merged, an error other than a failed zone stops the remaining zones.
+func (c *Client) zoneOperation(ctx context.Context, cmd string, zones []string) (*ZoneOperationResult, error) {
+	for _, zone := range zones {
+		if err := validateBulkArg(zone); err != nil {
+			return nil, err
+		}
+	}
+	if len(zones) == 0 {
+		return c.zoneOperationOnce(ctx, cmd, nil)
+	}
+	if len(zones) > 1 && c.dial == nil && c.pool == nil {
+		// Fail before changing anything rather than after the first zone
+		return nil, ErrSingleConnection
+	}
+
+	result := &ZoneOperationResult{
+		Failed: make(map[string]string),
+	}
+	for _, zone := range zones {
+		zoneResult, err := c.zoneOperationOnce(ctx, cmd+" "+zone, []string{zone})
+		if zoneResult == nil {
+			return result, err
+		}
+		result.Succeeded = append(result.Succeeded, zoneResult.Succeeded...)
+		maps.Copy(result.Failed, zoneResult.Failed)
+	}
+	return result, result.Err()
+}
+
+// zoneOperationOnce sends cmd for all zones or the single zone in zones
+func (c *Client) zoneOperationOnce(ctx context.Context, cmd string, zones []string) (_ *ZoneOperationResult, err error) {
+	c, err = c.begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer c.end(ctx, &err)
+
+	if err := c.sendCmd(cmd); err != nil {
+		return nil, err
+	}
+
+	return parseZoneOperationReply(c, zones)
+}
+
+// parseZoneOperationReply parses the reply to a command for all zones, or for the single zone in zones
+func parseZoneOperationReply(c replyReader, zones []string) (*ZoneOperationResult, error) {
+	reply, err := c.readReply()
+	if err != nil {
+		return nil, err
+	}
+	if len(reply) == 0 {
+		return nil, unexpectedReply(reply...)
+	}
+
+	result := &ZoneOperationResult{
+		All:    len(zones) == 0,
+		Failed: make(map[string]string),
+	}
+	hasOk := false
+	for _, line := range reply {
+		// Commands applied to all zones may reply with the number of zones, e.g. "ok, 3 zones"
+		if line == replyOK || strings.HasPrefix(line, replyOK+",") {
+			hasOk = true
+		} else if strings.HasPrefix(line, replyError) && len(zones) == 1 {
+			// Errors such as "error zone not secondary" do not name the zone
+			if prev, ok := result.Failed[zones[0]]; ok {
+				line = prev + "; " + line
+			}
+			result.Failed[zones[0]] = line
+		} else if strings.HasPrefix(line, replyError) {
+			return nil, newServerError(line)
+		} else {
+			return nil, unexpectedReply(line)
+		}
+	}
+	if !hasOk && len(result.Failed) == 0 {
+		return nil, unexpectedReply(reply...)
+	}
+
+	if hasOk {
+		for _, zone := range zones {
+			if !result.failed(zone) {
+				result.Succeeded = append(result.Succeeded, zone)
+			}
+		}
+	}
+	return result, result.Err()
 }
 
 // Repattern reloads the config file.
 // Alias of reconfig, https://github.com/NLnetLabs/nsd/blob/149049ca0a8e5536d2cfe60461b9f74d4f8ccc02/remote.c#L2640-L2643
-func (c *Client) Repattern() {
+// The returned report is populated even if an error is returned because NSD rejected the config file.
+func (c *Client) Repattern() (*ReconfigReport, error) {
+	return c.RepatternContext(context.Background())
+}
+
+// RepatternContext is like Repattern but uses ctx for the deadline and cancellation of the command
+func (c *Client) RepatternContext(ctx context.Context) (_ *ReconfigReport, err error) {
+	c, err = c.begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer c.end(ctx, &err)
+
 	// NSD handler: https://github.com/NLnetLabs/nsd/blob/NSD_4_11_0_REL/remote.c#L2047
-	//TODO implement me
-	panic("implement me")
+	if err := c.sendCmd(cmdRepattern); err != nil {
+		return nil, err
+	}
+
+	return parseReconfigReply(c)
+}
+
+// Reconfig reloads the config file.
+// Alias of repattern, see Repattern.
+func (c *Client) Reconfig() (*ReconfigReport, error) {
+	return c.ReconfigContext(context.Background())
+}
+
+// ReconfigContext is like Reconfig but uses ctx for the deadline and cancellation of the command
+func (c *Client) ReconfigContext(ctx context.Context) (_ *ReconfigReport, err error) {
+	c, err = c.begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer c.end(ctx, &err)
+
+	// NSD handler: https://github.com/NLnetLabs/nsd/blob/NSD_4_11_0_REL/remote.c#L2047
+	if err := c.sendCmd(cmdReconfig); err != nil {
+		return nil, err
+	}
+
+	return parseReconfigReply(c)
+}
+
+var reconfigStartRegex = regexp.MustCompile(`^reconfig start, read (?P<file>.+)$`)
+
+func parseReconfigReply(c replyReader) (*ReconfigReport, error) {
+	reply, err := c.readReply()
+	if err != nil {
+		return nil, err
+	}
+	if len(reply) == 0 {
+		return nil, unexpectedReply(reply...)
+	}
+
+	report := &ReconfigReport{}
+	for _, line := range reply {
+		if line == replyOK {
+			report.OK = true
+		} else if match := reconfigStartRegex.FindStringSubmatch(line); match != nil {
+			report.ConfigFile = match[reconfigStartRegex.SubexpIndex("file")]
+		} else {
+			// Config parser errors are passed through verbatim, and are not necessarily prefixed with "error"
+			report.Errors = append(report.Errors, line)
+		}
+	}
+	if !report.OK && len(report.Errors) == 0 {
+		return nil, unexpectedReply(reply...)
+	}
+	return report, report.Err()
 }
 
 // Reopen logfile (for log rotate)
 func (c *Client) LogReopen() error {
+	return c.LogReopenContext(context.Background())
+}
+
+// LogReopenContext is like LogReopen but uses ctx for the deadline and cancellation of the command
+func (c *Client) LogReopenContext(ctx context.Context) (err error) {
+	c, err = c.begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer c.end(ctx, &err)
+
 	// NSD handler: https://github.com/NLnetLabs/nsd/blob/NSD_4_11_0_REL/remote.c#L894
 	if err := c.sendCmd(cmdLogReopen); err != nil {
 		return err
@@ -113,68 +357,90 @@ func (c *Client) LogReopen() error {
 }
 
 // Status of server
-func (c *Client) Status() ([]string, error) {
+func (c *Client) Status() (*ServerStatus, error) {
+	return c.StatusContext(context.Background())
+}
+
+// StatusContext is like Status but uses ctx for the deadline and cancellation of the command
+func (c *Client) StatusContext(ctx context.Context) (*ServerStatus, error) {
+	return withRetry(ctx, c, c.statusOnce)
+}
+
+// statusOnce runs Status once, see withRetry
+func (c *Client) statusOnce(ctx context.Context) (_ *ServerStatus, err error) {
+	c, err = c.begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer c.end(ctx, &err)
+
 	// NSD handler: https://github.com/NLnetLabs/nsd/blob/NSD_4_11_0_REL/remote.c#L1249
 	if err := c.sendCmd(cmdStatus); err != nil {
 		return nil, err
 	}
 
-	// Issue: There is no end of message
-	// https://github.com/NLnetLabs/nsd/blob/NSD_4_11_0_REL/remote.c#L1249
-	lines := make([]string, 0, 2)
-	for {
-		if reply, err := c.readLine(); err != nil {
-			return nil, err
-		} else {
-			if reply == "" {
-				return lines, nil
-			}
-			lines = append(lines, reply)
-		}
-	}
+	return parseStatusReply(c)
+}
+
+// Stats returns the statistics counters and resets them
+func (c *Client) Stats() (*Stats, error) {
+	return c.StatsContext(context.Background())
 }
 
-func (c *Client) Stats() ([]string, error) {
+// StatsContext is like Stats but uses ctx for the deadline and cancellation of the command
+func (c *Client) StatsContext(ctx context.Context) (_ *Stats, err error) {
+	c, err = c.begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer c.end(ctx, &err)
+
 	// NSD handler: https://github.com/NLnetLabs/nsd/blob/NSD_4_11_0_REL/remote.c#L1266
 	if err := c.sendCmd(cmdStats); err != nil {
 		return nil, err
 	}
 
-	// Issue: There is no end of message
-	// https://github.com/NLnetLabs/nsd/blob/NSD_4_11_0_REL/remote.c#L1249
-	lines := make([]string, 0, 2)
-	for {
-		if reply, err := c.readLine(); err != nil {
-			return nil, err
-		} else {
-			if reply == "" {
-				return lines, nil
-			}
-			lines = append(lines, reply)
-		}
-	}
+	return parseStatsReply(c)
 }
 
-func (c *Client) StatsNoReset() ([]string, error) {
+// StatsNoReset returns the statistics counters without resetting them
+func (c *Client) StatsNoReset() (*Stats, error) {
+	return c.StatsNoResetContext(context.Background())
+}
+
+// StatsNoResetContext is like StatsNoReset but uses ctx for the deadline and cancellation of the command
+func (c *Client) StatsNoResetContext(ctx context.Context) (*Stats, error) {
+	return withRetry(ctx, c, c.statsNoResetOnce)
+}
+
+// statsNoResetOnce runs StatsNoReset once, see withRetry
+func (c *Client) statsNoResetOnce(ctx context.Context) (_ *Stats, err error) {
+	c, err = c.begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer c.end(ctx, &err)
+
 	// NSD handler: https://github.com/NLnetLabs/nsd/blob/NSD_4_11_0_REL/remote.c#L1266
 	if err := c.sendCmd(cmdStatsNoReset); err != nil {
 		return nil, err
 	}
 
-	lines := make([]string, 0, 2)
-	for {
-		if reply, err := c.readLine(); err != nil {
-			return nil, err
-		} else {
-			if reply == "" {
-				return lines, nil
-			}
-			lines = append(lines, reply)
-		}
-	}
+	return parseStatsReply(c)
 }
 
 func (c *Client) AddZone(domain string, pattern string) error {
+	return c.AddZoneContext(context.Background(), domain, pattern)
+}
+
+// AddZoneContext is like AddZone but uses ctx for the deadline and cancellation of the command
+func (c *Client) AddZoneContext(ctx context.Context, domain string, pattern string) (err error) {
+	c, err = c.begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer c.end(ctx, &err)
+
 	// NSD handler: https://github.com/NLnetLabs/nsd/blob/NSD_4_11_0_REL/remote.c#L1532
 	cmd := fmt.Sprintf("%s %s %s", cmdAddZone, domain, pattern)
 	if err := c.sendCmd(cmd); err != nil {
@@ -185,6 +451,17 @@ func (c *Client) AddZone(domain string, pattern string) error {
 }
 
 func (c *Client) DelZone(domain string) error {
+	return c.DelZoneContext(context.Background(), domain)
+}
+
+// DelZoneContext is like DelZone but uses ctx for the deadline and cancellation of the command
+func (c *Client) DelZoneContext(ctx context.Context, domain string) (err error) {
+	c, err = c.begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer c.end(ctx, &err)
+
 	// NSD handler: https://github.com/NLnetLabs/nsd/blob/NSD_4_11_0_REL/remote.c#L1541
 	cmd := fmt.Sprintf("%s %s", cmdDelZone, domain)
 	if err := c.sendCmd(cmd); err != nil {
@@ -195,6 +472,17 @@ func (c *Client) DelZone(domain string) error {
 }
 
 func (c *Client) ChangeZone(domain string, pattern string) error {
+	return c.ChangeZoneContext(context.Background(), domain, pattern)
+}
+
+// ChangeZoneContext is like ChangeZone but uses ctx for the deadline and cancellation of the command
+func (c *Client) ChangeZoneContext(ctx context.Context, domain string, pattern string) (err error) {
+	c, err = c.begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer c.end(ctx, &err)
+
 	// NSD handler: https://github.com/NLnetLabs/nsd/blob/NSD_4_11_0_REL/remote.c#L1550
 	cmd := fmt.Sprintf("%s %s %s", cmdChangeZone, domain, pattern)
 	if err := c.sendCmd(cmd); err != nil {
@@ -204,91 +492,496 @@ func (c *Client) ChangeZone(domain string, pattern string) error {
 	return expectOk(c)
 }
 
-func (c *Client) Write(zones []string) {
+// AddZones adds many zones in a single addzones session.
+// The returned result is populated even if an error is returned for zones that could not be added.
+func (c *Client) AddZones(zones []ZonePattern) (*ZoneOperationResult, error) {
+	return c.AddZonesContext(context.Background(), zones)
+}
+
+// AddZonesContext is like AddZones but uses ctx for the deadline and cancellation of the command
+func (c *Client) AddZonesContext(ctx context.Context, zones []ZonePattern) (_ *ZoneOperationResult, err error) {
+	c, err = c.begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer c.end(ctx, &err)
+
+	// NSD handler: https://github.com/NLnetLabs/nsd/blob/NSD_4_11_0_REL/remote.c (do_addzones)
+	names := make([]string, len(zones))
+	lines := make([]string, len(zones))
+	for i, zone := range zones {
+		if err := validateBulkArg(zone.Zone); err != nil {
+			return nil, err
+		}
+		if err := validateBulkArg(zone.Pattern); err != nil {
+			return nil, err
+		}
+		names[i] = zone.Zone
+		lines[i] = zone.Zone + " " + zone.Pattern
+	}
+
+	return c.bulkZoneCmd(cmdAddZones, names, lines)
+}
+
+// DelZones deletes many zones in a single delzones session.
+// The returned result is populated even if an error is returned for zones that could not be deleted.
+func (c *Client) DelZones(zones []string) (*ZoneOperationResult, error) {
+	return c.DelZonesContext(context.Background(), zones)
+}
+
+// DelZonesContext is like DelZones but uses ctx for the deadline and cancellation of the command
+func (c *Client) DelZonesContext(ctx context.Context, zones []string) (_ *ZoneOperationResult, err error) {
+	c, err = c.begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer c.end(ctx, &err)
+
+	// NSD handler: https://github.com/NLnetLabs/nsd/blob/NSD_4_11_0_REL/remote.c (do_delzones)
+	for _, zone := range zones {
+		if err := validateBulkArg(zone); err != nil {
+			return nil, err
+		}
+	}
+
+	return c.bulkZoneCmd(cmdDelZones, zones, zones)
+}
+
+// validateBulkArg rejects arguments that would corrupt the line based bulk session
+func validateBulkArg(arg string) error {
+	if arg == "" {
+		return fmt.Errorf("missing argument")
+	} else if strings.ContainsAny(arg, " \t\r\n\x04") {
+		return fmt.Errorf("invalid argument: %q", arg)
+	}
+	return nil
+}
+
+// bulkZoneCmd sends cmd followed by one line per zone, terminated by an end of transmission line
+func (c *Client) bulkZoneCmd(cmd string, zones []string, lines []string) (*ZoneOperationResult, error) {
+	if len(lines) == 0 {
+		return &ZoneOperationResult{Failed: make(map[string]string)}, nil
+	}
+
+	// The server replies to failed lines while we are still sending,
+	// so the reply is read concurrently to avoid both ends blocking on full buffers
+	type bulkReply struct {
+		result *ZoneOperationResult
+		err    error
+	}
+	replies := make(chan bulkReply, 1)
+	c.traceSend(cmd)
+	go func() {
+		result, err := parseBulkZoneReply(c, zones, lines)
+		replies <- bulkReply{result, err}
+	}()
+
+	w := bufio.NewWriter(c.socket)
+	_, err := w.WriteString(cmd + "\n")
+	for _, line := range lines {
+		if err != nil {
+			break
+		}
+		_, err = w.WriteString(line + "\n")
+	}
+	if err == nil {
+		_, err = w.WriteString(endOfTransmission + "\n")
+	}
+	if err == nil {
+		err = w.Flush()
+	}
+	if err != nil {
+		// The session is in an unknown state, closing unblocks the reader
+		_ = c.socket.Close()
+		<-replies
+		return nil, err
+	}
+
+	reply := <-replies
+	return reply.result, reply.err
+}
+
+var bulkSummaryRegex = regexp.MustCompile(`^(?:added|deleted):? (?P<count>\d+)`)
+
+// parseBulkZoneReply parses the reply to addzones or delzones, lines are the input lines sent for zones.
+// NSD handles the input lines in order, so each error is matched to the first line from the current one that it names,
+// by its zone or another field such as the pattern. Errors naming no line belong to a line between the neighbouring
+// matched errors, those lines are counted as failed only if the summary proves it, and as neither succeeded nor failed otherwise.
+func parseBulkZoneReply(c replyReader, zones []string, lines []string) (*ZoneOperationResult, error) {
+	reply, err := c.readReply()
+	if err != nil {
+		return nil, err
+	}
+
+	failed := make([]string, len(zones))
+	succeeded := make([]bool, len(zones))
+	uncertain := make([]bool, len(zones))
+	// next is the first input line the server may still reply about
+	next := 0
+	// unresolved is the first line an unmatched error may belong to, -1 if there is none
+	unresolved := -1
+	markUncertain := func(end int) {
+		if unresolved < 0 {
+			return
+		}
+		for i := unresolved; i < end; i++ {
+			uncertain[i] = true
+		}
+		unresolved = -1
+	}
+	var unattributed []string
+	count := -1
+	for _, line := range reply {
+		if i := matchBulkEcho(line, lines, next); i >= 0 {
+			// Success lines echo the input line, e.g. "added: example.com replica"
+			markUncertain(i)
+			succeeded[i] = true
+			next = i + 1
+			continue
+		}
+		if match := bulkSummaryRegex.FindStringSubmatch(line); match != nil {
+			count, _ = strconv.Atoi(match[bulkSummaryRegex.SubexpIndex("count")])
+			continue
+		}
+
+		// Errors name the line in various ways, e.g. "error zone example.com already exists",
+		// "error pattern nope does not exist" or "error for input line 'example.com replica'"
+		i := matchBulkError(line, zones, lines, next)
+		if i < 0 {
+			if unresolved < 0 {
+				unresolved = next
+			}
+			unattributed = append(unattributed, line)
+			continue
+		}
+		// The unmatched errors may also belong to this line
+		markUncertain(i + 1)
+		if failed[i] != "" {
+			failed[i] += "; " + line
+		} else {
+			failed[i] = line
+		}
+		next = i
+		if strings.HasPrefix(line, "error for input line") {
+			// The last error of an input line
+			next = i + 1
+		}
+	}
+	if count < 0 {
+		return nil, unexpectedReply(reply...)
+	}
+	markUncertain(len(zones))
+
+	// The summary counts the added or deleted zones, which resolves the uncertain lines if they all succeeded or all failed
+	notFailed, uncertainCount := 0, 0
+	for i := range zones {
+		if failed[i] == "" && !succeeded[i] {
+			notFailed++
+			if uncertain[i] {
+				uncertainCount++
+			}
+		}
+	}
+	explicit := 0
+	for _, ok := range succeeded {
+		if ok {
+			explicit++
+		}
+	}
+	missing := notFailed + explicit - count
+	result := &ZoneOperationResult{
+		Failed: make(map[string]string),
+	}
+	for i, zone := range zones {
+		switch {
+		case failed[i] != "":
+			if prev, ok := result.Failed[zone]; ok {
+				result.Failed[zone] = prev + "; " + failed[i]
+			} else {
+				result.Failed[zone] = failed[i]
+			}
+		case succeeded[i] || !uncertain[i] || missing == 0:
+			result.Succeeded = append(result.Succeeded, zone)
+		case missing == uncertainCount:
+			result.Failed[zone] = strings.Join(unattributed, "; ")
+		}
+	}
+	if len(unattributed) > 0 {
+		return result, newServerError(strings.Join(unattributed, "; "))
+	}
+	return result, result.Err()
+}
+
+// matchBulkEcho returns the index of the input line from next that line reports as done, or -1
+func matchBulkEcho(line string, lines []string, next int) int {
+	for _, prefix := range []string{"added: ", "deleted: "} {
+		if echo, ok := strings.CutPrefix(line, prefix); ok {
+			for i := next; i < len(lines); i++ {
+				if lines[i] == echo {
+					return i
+				}
+			}
+		}
+	}
+	return -1
+}
+
+// matchBulkError returns the index of the first input line from next named by an error line, preferring zone names
+// over other fields, or -1
+func matchBulkError(line string, zones []string, lines []string, next int) int {
+	var fields []string
+	for _, field := range strings.Fields(line) {
+		fields = append(fields, strings.Trim(field, `'":,`))
+	}
+	for i := next; i < len(zones); i++ {
+		for _, field := range fields {
+			if normalizeZone(field) == normalizeZone(zones[i]) {
+				return i
+			}
+		}
+	}
+	for i := next; i < len(lines); i++ {
+		for _, arg := range strings.Fields(lines[i])[1:] {
+			if slices.Contains(fields, arg) {
+				return i
+			}
+		}
+	}
+	return -1
+}
+
+// Write causes NSD to write modified zones to their zone files.
+// If no zones are given all zones are written.
+// The returned result is populated even if an error is returned for zones that failed.
+// Several zones need a dialing or pooled client, see Reload.
+func (c *Client) Write(zones []string) (*ZoneOperationResult, error) {
+	return c.WriteContext(context.Background(), zones)
+}
+
+// WriteContext is like Write but uses ctx for the deadline and cancellation of the command
+func (c *Client) WriteContext(ctx context.Context, zones []string) (*ZoneOperationResult, error) {
 	// NSD handler: https://github.com/NLnetLabs/nsd/blob/NSD_4_11_0_REL/remote.c#L915
-	//TODO implement me
-	panic("implement me")
+	return c.zoneOperation(ctx, cmdWrite, zones)
 }
 
-func (c *Client) Notify(zones []string) {
+// Notify sends NOTIFY messages to the secondaries of the zones.
+// If no zones are given NOTIFY messages are sent for all zones.
+// The returned result is populated even if an error is returned for zones that failed.
+// Several zones need a dialing or pooled client, see Reload.
+func (c *Client) Notify(zones []string) (*ZoneOperationResult, error) {
+	return c.NotifyContext(context.Background(), zones)
+}
+
+// NotifyContext is like Notify but uses ctx for the deadline and cancellation of the command
+func (c *Client) NotifyContext(ctx context.Context, zones []string) (*ZoneOperationResult, error) {
 	// NSD handler: https://github.com/NLnetLabs/nsd/blob/NSD_4_11_0_REL/remote.c#L928
-	//TODO implement me
-	panic("implement me")
+	return c.zoneOperation(ctx, cmdNotify, zones)
+}
+
+// Transfer attempts to update secondary zones by checking the primaries for a newer serial.
+// If no zones are given all secondary zones are checked.
+// The returned result is populated even if an error is returned for zones that failed.
+// Several zones need a dialing or pooled client, see Reload.
+func (c *Client) Transfer(zones []string) (*ZoneOperationResult, error) {
+	return c.TransferContext(context.Background(), zones)
 }
 
-func (c *Client) Transfer(zones []string) {
+// TransferContext is like Transfer but uses ctx for the deadline and cancellation of the command
+func (c *Client) TransferContext(ctx context.Context, zones []string) (*ZoneOperationResult, error) {
 	// NSD handler: https://github.com/NLnetLabs/nsd/blob/NSD_4_11_0_REL/remote.c#L953
-	//TODO implement me
-	panic("implement me")
+	return c.zoneOperation(ctx, cmdTransfer, zones)
 }
 
-func (c *Client) ForceTransfer(zones []string) {
+// ForceTransfer performs a full zone transfer (AXFR) of secondary zones from their primaries, regardless of serial.
+// If no zones are given all secondary zones are transferred.
+// The returned result is populated even if an error is returned for zones that failed.
+// Several zones need a dialing or pooled client, see Reload.
+func (c *Client) ForceTransfer(zones []string) (*ZoneOperationResult, error) {
+	return c.ForceTransferContext(context.Background(), zones)
+}
+
+// ForceTransferContext is like ForceTransfer but uses ctx for the deadline and cancellation of the command
+func (c *Client) ForceTransferContext(ctx context.Context, zones []string) (*ZoneOperationResult, error) {
 	// NSD handler: https://github.com/NLnetLabs/nsd/blob/NSD_4_11_0_REL/remote.c#L994
-	//TODO implement me
-	panic("implement me")
+	return c.zoneOperation(ctx, cmdForceTransfer, zones)
 }
 
 func (c *Client) ZoneStatus(zone string) (*ZoneStatus, error) {
+	return c.ZoneStatusContext(context.Background(), zone)
+}
+
+// ZoneStatusContext is like ZoneStatus but uses ctx for the deadline and cancellation of the command
+func (c *Client) ZoneStatusContext(ctx context.Context, zone string) (*ZoneStatus, error) {
+	return withRetry(ctx, c, func(ctx context.Context) (*ZoneStatus, error) {
+		return c.zoneStatusOnce(ctx, zone)
+	})
+}
+
+// zoneStatusOnce runs ZoneStatus once, see withRetry
+func (c *Client) zoneStatusOnce(ctx context.Context, zone string) (_ *ZoneStatus, err error) {
+	c, err = c.begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer c.end(ctx, &err)
+
 	// NSD handler: https://github.com/NLnetLabs/nsd/blob/NSD_4_11_0_REL/remote.c#L1033
-	if err := c.sendCmd(cmdServerPID); err != nil {
+	cmd := fmt.Sprintf("%s %s", cmdZoneStatus, zone)
+	if err := c.sendCmd(cmd); err != nil {
 		return nil, err
 	}
 
 	return parseZoneStatus(c)
 }
 
+// ZoneStatuses returns the status of every configured zone matching all the filters
+func (c *Client) ZoneStatuses(filters ...ZoneStatusFilter) ([]ZoneStatus, error) {
+	return c.ZoneStatusesContext(context.Background(), filters...)
+}
+
+// ZoneStatusesContext is like ZoneStatuses but uses ctx for the deadline and cancellation of the command
+func (c *Client) ZoneStatusesContext(ctx context.Context, filters ...ZoneStatusFilter) ([]ZoneStatus, error) {
+	return withRetry(ctx, c, func(ctx context.Context) ([]ZoneStatus, error) {
+		return c.zoneStatusesOnce(ctx, filters...)
+	})
+}
+
+// zoneStatusesOnce runs ZoneStatuses once, see withRetry
+func (c *Client) zoneStatusesOnce(ctx context.Context, filters ...ZoneStatusFilter) (_ []ZoneStatus, err error) {
+	c, err = c.begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer c.end(ctx, &err)
+
+	// NSD handler: https://github.com/NLnetLabs/nsd/blob/NSD_4_11_0_REL/remote.c#L1033
+	if err := c.sendCmd(cmdZoneStatus); err != nil {
+		return nil, err
+	}
+
+	return parseZoneStatuses(c, filters...)
+}
+
 func parseZoneStatus(c replyReader) (*ZoneStatus, error) {
-	// NSD handler:
-	status := &ZoneStatus{
-		Attributes: make(map[string]string),
+	statuses, err := parseZoneStatuses(c)
+	if err != nil {
+		return nil, err
+	} else if len(statuses) != 1 {
+		return nil, malformedReply("expected reply to contain a single zone", nil)
 	}
-	hasZone := false
-	hasState := false
+	return &statuses[0], nil
+}
 
+func parseZoneStatuses(c replyReader, filters ...ZoneStatusFilter) ([]ZoneStatus, error) {
 	reply, err := c.readReply()
 	if err != nil {
 		return nil, err
 	}
-	for _, line := range reply {
+
+	var statuses []ZoneStatus
+	err = zoneStatusSeq(replyLinesOf(reply), filters, func(status ZoneStatus) bool {
+		statuses = append(statuses, status)
+		return true
+	})
+	if err != nil {
+		return nil, err
+	}
+	return statuses, nil
+}
+
+// zoneStatusSeq parses the lines of a zonestatus reply, yielding every zone matching all filters once its block is complete
+func zoneStatusSeq(lines iter.Seq2[string, error], filters []ZoneStatusFilter, yield func(ZoneStatus) bool) error {
+	var status *ZoneStatus
+	hasState := false
+	// completeStatus yields the zone currently being parsed, it reports false once yield asked to stop
+	completeStatus := func() (bool, error) {
+		if status == nil {
+			return true, nil
+		}
+		if !hasState {
+			return false, malformedReply("expected reply to contain zone name and state", nil)
+		}
+		for _, filter := range filters {
+			if !filter(status) {
+				return true, nil
+			}
+		}
+		return yield(*status), nil
+	}
+
+	for line, err := range lines {
+		if err != nil {
+			return err
+		}
 		if strings.HasPrefix(line, replyError) {
-			return nil, fmt.Errorf("server send error: %s", reply)
+			return newServerError(line)
 		}
 
 		match := commonKeyValueRegex.FindStringSubmatch(line)
 		if match == nil {
-			return nil, fmt.Errorf("unexpected reply: %s", line)
+			return unexpectedReply(line)
 		}
 
 		key := match[commonKeyValueRegex.SubexpIndex("key")]
 		value := match[commonKeyValueRegex.SubexpIndex("value")]
-		switch key {
-		case "zone":
-			status.Zone = value
-			hasZone = true
-		case "state":
-			status.State = value
+		if key == "zone" {
+			// Each zone starts a new block
+			if more, err := completeStatus(); !more || err != nil {
+				return err
+			}
+			status = &ZoneStatus{
+				Zone:       value,
+				Attributes: make(map[string]string),
+			}
+			hasState = false
+			continue
+		} else if status == nil {
+			return malformedReply("expected reply to start with zone name", nil, line)
+		}
+
+		if err := status.setAttribute(key, value); err != nil {
+			return malformedReply("zone "+status.Zone, err, line)
+		}
+		if key == "state" {
 			hasState = true
-		default:
-			status.Attributes[key] = value
 		}
 	}
-	if !hasZone || !hasState {
-		return nil, fmt.Errorf("malformed reply, expected reply to contain zone name and state")
-	}
-	return status, nil
+	// An empty reply is a server without zones, the reply to a single zone is checked by parseZoneStatus
+	_, err := completeStatus()
+	return err
 }
 
 func (c *Client) ServerPID() (int, error) {
+	return c.ServerPIDContext(context.Background())
+}
+
+// ServerPIDContext is like ServerPID but uses ctx for the deadline and cancellation of the command
+func (c *Client) ServerPIDContext(ctx context.Context) (int, error) {
+	return withRetry(ctx, c, c.serverPIDOnce)
+}
+
+// serverPIDOnce runs ServerPID once, see withRetry
+func (c *Client) serverPIDOnce(ctx context.Context) (_ int, err error) {
+	c, err = c.begin(ctx)
+	if err != nil {
+		return -1, err
+	}
+	defer c.end(ctx, &err)
+
 	// NSD handler: https://github.com/NLnetLabs/nsd/blob/NSD_4_11_0_REL/remote.c#L2130
 	if err := c.sendCmd(cmdServerPID); err != nil {
 		return -1, err
 	}
 
-	if reply, err := c.readLine(); err != nil {
+	if reply, err := c.readReply(); err != nil {
 		return -1, err
+	} else if len(reply) != 1 {
+		return -1, unexpectedReply(reply...)
+	} else if strings.HasPrefix(reply[0], replyError) {
+		return -1, newServerError(reply[0])
 	} else {
-		if v, err := strconv.Atoi(reply); err != nil {
-			return -1, err
+		if v, err := strconv.Atoi(reply[0]); err != nil {
+			return -1, malformedReply("expected process id", err, reply[0])
 		} else {
 			return v, nil
 		}
@@ -296,6 +989,17 @@ func (c *Client) ServerPID() (int, error) {
 }
 
 func (c *Client) Verbosity(verbosity int) error {
+	return c.VerbosityContext(context.Background(), verbosity)
+}
+
+// VerbosityContext is like Verbosity but uses ctx for the deadline and cancellation of the command
+func (c *Client) VerbosityContext(ctx context.Context, verbosity int) (err error) {
+	c, err = c.begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer c.end(ctx, &err)
+
 	// NSD handler: https://github.com/NLnetLabs/nsd/blob/NSD_4_11_0_REL/remote.c#L1187
 	cmd := fmt.Sprintf("%s %d", cmdVerbosity, verbosity)
 	if err := c.sendCmd(cmd); err != nil {
@@ -305,37 +1009,198 @@ func (c *Client) Verbosity(verbosity int) error {
 	return expectOk(c)
 }
 
-func (c *Client) GetTSig(keyName []string) {
+// GetTSigs returns all TSIG keys configured in NSD
+func (c *Client) GetTSigs() ([]TsigKey, error) {
+	return c.GetTSigsContext(context.Background())
+}
+
+// GetTSigsContext is like GetTSigs but uses ctx for the deadline and cancellation of the command
+func (c *Client) GetTSigsContext(ctx context.Context) ([]TsigKey, error) {
+	return withRetry(ctx, c, c.getTSigsOnce)
+}
+
+// getTSigsOnce runs GetTSigs once, see withRetry
+func (c *Client) getTSigsOnce(ctx context.Context) (_ []TsigKey, err error) {
+	c, err = c.begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer c.end(ctx, &err)
+
+	// NSD handler: https://github.com/NLnetLabs/nsd/blob/NSD_4_11_0_REL/remote.c#L2137
+	if err := c.sendCmd(cmdPrintTsig); err != nil {
+		return nil, err
+	}
+
+	return parseTsigKeysReply(c)
+}
+
+// GetTSig returns the TSIG key with the given name, or ErrKeyNotFound
+func (c *Client) GetTSig(keyName string) (*TsigKey, error) {
+	return c.GetTSigContext(context.Background(), keyName)
+}
+
+// GetTSigContext is like GetTSig but uses ctx for the deadline and cancellation of the command
+func (c *Client) GetTSigContext(ctx context.Context, keyName string) (*TsigKey, error) {
+	return withRetry(ctx, c, func(ctx context.Context) (*TsigKey, error) {
+		return c.getTSigOnce(ctx, keyName)
+	})
+}
+
+// getTSigOnce runs GetTSig once, see withRetry
+func (c *Client) getTSigOnce(ctx context.Context, keyName string) (_ *TsigKey, err error) {
+	c, err = c.begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer c.end(ctx, &err)
+
 	// NSD handler: https://github.com/NLnetLabs/nsd/blob/NSD_4_11_0_REL/remote.c#L2137
-	//TODO implement me
-	panic("implement me")
+	if err := validateTsigName(keyName); err != nil {
+		return nil, err
+	}
+	cmd := fmt.Sprintf("%s %s", cmdPrintTsig, keyName)
+	if err := c.sendCmd(cmd); err != nil {
+		return nil, err
+	}
+
+	keys, err := parseTsigKeysReply(c)
+	if err != nil {
+		return nil, err
+	} else if len(keys) != 1 {
+		return nil, malformedReply("expected reply to contain a single key", nil)
+	}
+	return &keys[0], nil
 }
 
-func (c *Client) UpdateTSig(name string, secret string) {
+// UpdateTSig replaces the secret of an existing TSIG key
+func (c *Client) UpdateTSig(name string, secret string) error {
+	return c.UpdateTSigContext(context.Background(), name, secret)
+}
+
+// UpdateTSigContext is like UpdateTSig but uses ctx for the deadline and cancellation of the command
+func (c *Client) UpdateTSigContext(ctx context.Context, name string, secret string) (err error) {
+	c, err = c.begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer c.end(ctx, &err)
+
 	// NSD handler: https://github.com/NLnetLabs/nsd/blob/NSD_4_11_0_REL/remote.c#L2159
-	//TODO implement me
-	panic("implement me")
+	if err := validateTsigName(name); err != nil {
+		return err
+	}
+	if err := validateTsigSecret(secret); err != nil {
+		return err
+	}
+	cmd := fmt.Sprintf("%s %s %s", cmdUpdateTsig, name, secret)
+	if err := c.sendCmd(cmd); err != nil {
+		return err
+	}
+
+	return expectTsigOk(c, name, "")
 }
 
-func (c *Client) AddTSig(name string, secret string, algo *string) {
+// AddTSig adds a new TSIG key, if algo is nil no algorithm is sent and NSD uses its default, hmac-sha256
+func (c *Client) AddTSig(name string, secret string, algo *TsigAlgorithm) error {
+	return c.AddTSigContext(context.Background(), name, secret, algo)
+}
+
+// AddTSigContext is like AddTSig but uses ctx for the deadline and cancellation of the command
+func (c *Client) AddTSigContext(ctx context.Context, name string, secret string, algo *TsigAlgorithm) (err error) {
+	c, err = c.begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer c.end(ctx, &err)
+
 	// NSD handler: https://github.com/NLnetLabs/nsd/blob/NSD_4_11_0_REL/remote.c#L2210
-	//TODO implement me
-	panic("implement me")
+	if err := validateTsigName(name); err != nil {
+		return err
+	}
+	if err := validateTsigSecret(secret); err != nil {
+		return err
+	}
+	cmd := fmt.Sprintf("%s %s %s", cmdAddTsig, name, secret)
+	// Without an algorithm the server picks its default
+	if algo != nil {
+		if !algo.Valid() {
+			return fmt.Errorf("%w: %s", ErrUnsupportedAlgorithm, *algo)
+		}
+		cmd += " " + string(*algo)
+	}
+	if err := c.sendCmd(cmd); err != nil {
+		return err
+	}
+
+	return expectTsigOk(c, name, "")
+}
+
+// AssocTSig associates a TSIG key with a zone
+func (c *Client) AssocTSig(zone string, keyName string) error {
+	return c.AssocTSigContext(context.Background(), zone, keyName)
 }
 
-func (c *Client) AssocTSig(zone string, keyName string) {
+// AssocTSigContext is like AssocTSig but uses ctx for the deadline and cancellation of the command
+func (c *Client) AssocTSigContext(ctx context.Context, zone string, keyName string) (err error) {
+	c, err = c.begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer c.end(ctx, &err)
+
 	// NSD handler: https://github.com/NLnetLabs/nsd/blob/NSD_4_11_0_REL/remote.c#L2289
-	//TODO implement me
-	panic("implement me")
+	if err := validateBulkArg(zone); err != nil {
+		return err
+	}
+	if err := validateTsigName(keyName); err != nil {
+		return err
+	}
+	cmd := fmt.Sprintf("%s %s %s", cmdAssociateTsig, zone, keyName)
+	if err := c.sendCmd(cmd); err != nil {
+		return err
+	}
+
+	return expectTsigOk(c, keyName, zone)
+}
+
+// DelTSig deletes a TSIG key, a *KeyInUseError is returned if a zone still uses the key
+func (c *Client) DelTSig(keyName string) error {
+	return c.DelTSigContext(context.Background(), keyName)
 }
 
-func (c *Client) DelTSig(keyName string) {
+// DelTSigContext is like DelTSig but uses ctx for the deadline and cancellation of the command
+func (c *Client) DelTSigContext(ctx context.Context, keyName string) (err error) {
+	c, err = c.begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer c.end(ctx, &err)
+
 	// NSD handler: https://github.com/NLnetLabs/nsd/blob/NSD_4_11_0_REL/remote.c#L2348
-	//TODO implement me
-	panic("implement me")
+	if err := validateTsigName(keyName); err != nil {
+		return err
+	}
+	cmd := fmt.Sprintf("%s %s", cmdDeleteTsig, keyName)
+	if err := c.sendCmd(cmd); err != nil {
+		return err
+	}
+
+	return expectTsigOk(c, keyName, "")
 }
 
 func (c *Client) AddCookieSecret(secret string) error {
+	return c.AddCookieSecretContext(context.Background(), secret)
+}
+
+// AddCookieSecretContext is like AddCookieSecret but uses ctx for the deadline and cancellation of the command
+func (c *Client) AddCookieSecretContext(ctx context.Context, secret string) (err error) {
+	c, err = c.begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer c.end(ctx, &err)
+
 	// NSD handler: https://github.com/NLnetLabs/nsd/blob/NSD_4_11_0_REL/remote.c#L2500
 	cmd := fmt.Sprintf("%s %s", cmdAddCookieSecret, secret)
 	if err := c.sendCmd(cmd); err != nil {
@@ -350,22 +1215,33 @@ func parseAddCookieSecretReply(c replyReader) error {
 	if err != nil {
 		return err
 	}
-	if len(reply) == 2 && reply[0] == "invalid cookie secret: invalid argument length" {
-		return fmt.Errorf("invalid argument length")
+	if len(reply) == 2 && strings.HasPrefix(reply[0], "invalid cookie secret") {
+		return newServerError(reply[0])
 	} else if len(reply) == 1 {
 		if reply[0] == replyOK {
 			return nil
 		} else if strings.HasPrefix(reply[0], replyError) {
-			return fmt.Errorf("server send error: %s", reply)
+			return newServerError(reply[0])
 		} else {
-			return fmt.Errorf("unexpected reply: %s", reply)
+			return unexpectedReply(reply...)
 		}
 	} else {
-		return fmt.Errorf("unexpected reply: %s", reply)
+		return unexpectedReply(reply...)
 	}
 }
 
 func (c *Client) DropCookieSecret() error {
+	return c.DropCookieSecretContext(context.Background())
+}
+
+// DropCookieSecretContext is like DropCookieSecret but uses ctx for the deadline and cancellation of the command
+func (c *Client) DropCookieSecretContext(ctx context.Context) (err error) {
+	c, err = c.begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer c.end(ctx, &err)
+
 	// NSD handler: https://github.com/NLnetLabs/nsd/blob/NSD_4_11_0_REL/remote.c#L2474
 	if err := c.sendCmd(cmdDropCookieSecret); err != nil {
 		return err
@@ -375,6 +1251,17 @@ func (c *Client) DropCookieSecret() error {
 }
 
 func (c *Client) ActivateCookieSecret() error {
+	return c.ActivateCookieSecretContext(context.Background())
+}
+
+// ActivateCookieSecretContext is like ActivateCookieSecret but uses ctx for the deadline and cancellation of the command
+func (c *Client) ActivateCookieSecretContext(ctx context.Context) (err error) {
+	c, err = c.begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer c.end(ctx, &err)
+
 	// NSD handler: https://github.com/NLnetLabs/nsd/blob/NSD_4_11_0_REL/remote.c#L2448
 	if err := c.sendCmd(cmdActivateCookieSecret); err != nil {
 		return err
@@ -386,6 +1273,22 @@ func (c *Client) ActivateCookieSecret() error {
 var commonKeyValueRegex = regexp.MustCompile(`^\s*(?P<key>[^:\s]+)\s*:\s+"?(?P<value>[^"].*?)"?$`)
 
 func (c *Client) GetCookieSecrets() (*CookieSecrets, error) {
+	return c.GetCookieSecretsContext(context.Background())
+}
+
+// GetCookieSecretsContext is like GetCookieSecrets but uses ctx for the deadline and cancellation of the command
+func (c *Client) GetCookieSecretsContext(ctx context.Context) (*CookieSecrets, error) {
+	return withRetry(ctx, c, c.getCookieSecretsOnce)
+}
+
+// getCookieSecretsOnce runs GetCookieSecrets once, see withRetry
+func (c *Client) getCookieSecretsOnce(ctx context.Context) (_ *CookieSecrets, err error) {
+	c, err = c.begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer c.end(ctx, &err)
+
 	// NSD handler: https://github.com/NLnetLabs/nsd/blob/NSD_4_11_0_REL/remote.c#L2549
 	if err := c.sendCmd(cmdPrintCookieSecrets); err != nil {
 		return nil, err
@@ -402,7 +1305,7 @@ func parseCookieSecretsReply(c replyReader) (*CookieSecrets, error) {
 	}
 	for _, line := range reply {
 		if strings.HasPrefix(line, replyError) {
-			return nil, fmt.Errorf("server send error: %s", reply)
+			return nil, newServerError(line)
 		}
 
 		match := commonKeyValueRegex.FindStringSubmatch(line)
@@ -1,9 +1,14 @@
 package client
 
 import (
+	"bufio"
+	"errors"
+	"fmt"
+	"net"
 	"reflect"
 	"strings"
 	"testing"
+	"time"
 )
 
 type StaticReply []string
@@ -152,12 +157,12 @@ func Test_parseZoneStatus(t *testing.T) {
 			},
 			want: &ZoneStatus{
 				Zone:  "example.org",
-				State: "refreshing",
-				Attributes: map[string]string{
-					"served-serial": "none",
-					"commit-serial": "none",
-					"wait":          "99 sec between attempts",
+				State: ZoneStateRefreshing,
+				Wait: &ZoneWait{
+					Duration: 99 * time.Second,
+					Reason:   "between attempts",
 				},
+				Attributes: map[string]string{},
 			},
 			wantErr: false,
 		},
@@ -169,11 +174,10 @@ func Test_parseZoneStatus(t *testing.T) {
 	state: primary`, "\n")),
 			},
 			want: &ZoneStatus{
-				Zone:  "example.dk.",
-				State: "primary",
-				Attributes: map[string]string{
-					"pattern": "replica",
-				},
+				Zone:       "example.dk.",
+				State:      ZoneStatePrimary,
+				Pattern:    "replica",
+				Attributes: map[string]string{},
 			},
 			wantErr: false,
 		},
@@ -185,11 +189,69 @@ func Test_parseZoneStatus(t *testing.T) {
 			},
 			want: &ZoneStatus{
 				Zone:       "example.com",
-				State:      "primary",
+				State:      ZoneStatePrimary,
+				Attributes: map[string]string{},
+			},
+			wantErr: false,
+		},
+		{
+			name: "master zone of older releases",
+			args: args{
+				NewStaticReply(strings.Split(`zone:	example.com
+	state: master`, "\n")),
+			},
+			want: &ZoneStatus{
+				Zone:       "example.com",
+				State:      ZoneStatePrimary,
+				Attributes: map[string]string{},
+			},
+			wantErr: false,
+		},
+		{
+			name: "secondary zone transferring",
+			args: args{
+				NewStaticReply(strings.Split(`zone:	example.net
+	catalog-member-id: "4d9e6cbe.zones.catalog.invalid."
+	state: ok
+	served-serial: "2024010101 since 2024-01-01T10:00:00"
+	commit-serial: "2024010102 since 2024-01-01T12:30:00"
+	wait: "3600 sec until refresh"
+	transfer: "sent UDP to 192.0.2.1"`, "\n")),
+			},
+			want: &ZoneStatus{
+				Zone:            "example.net",
+				State:           ZoneStateOK,
+				CatalogMemberID: "4d9e6cbe.zones.catalog.invalid.",
+				ServedSerial: &ZoneSerial{
+					Serial: 2024010101,
+					Since:  time.Date(2024, 1, 1, 10, 0, 0, 0, time.Local),
+				},
+				CommitSerial: &ZoneSerial{
+					Serial: 2024010102,
+					Since:  time.Date(2024, 1, 1, 12, 30, 0, 0, time.Local),
+				},
+				Wait: &ZoneWait{
+					Duration: time.Hour,
+					Reason:   "until refresh",
+				},
+				Transfer: &ZoneTransfer{
+					Status:  "sent UDP",
+					Address: "192.0.2.1",
+				},
 				Attributes: map[string]string{},
 			},
 			wantErr: false,
 		},
+		{
+			name: "malformed serial",
+			args: args{
+				NewStaticReply(strings.Split(`zone:	example.net
+	state: ok
+	served-serial: "4294967296 since 2024-01-01T10:00:00"`, "\n")),
+			},
+			want:    nil,
+			wantErr: true,
+		},
 		{
 			name: "not configured zone",
 			args: args{
@@ -253,3 +315,536 @@ please provide a 128bit hex encoded secret`, "\n")),
 		})
 	}
 }
+
+func Test_parseZoneOperationReply(t *testing.T) {
+	type args struct {
+		c     replyReader
+		zones []string
+	}
+	tests := []struct {
+		name    string
+		args    args
+		want    *ZoneOperationResult
+		wantErr bool
+	}{
+		{
+			name: "all zones",
+			args: args{
+				c: NewStaticReply([]string{"ok"}),
+			},
+			want: &ZoneOperationResult{
+				All:    true,
+				Failed: map[string]string{},
+			},
+			wantErr: false,
+		},
+		{
+			name: "only zone not configured",
+			args: args{
+				c:     NewStaticReply([]string{"error zone example.net not configured"}),
+				zones: []string{"example.net"},
+			},
+			want: &ZoneOperationResult{
+				Failed: map[string]string{
+					"example.net": "error zone example.net not configured",
+				},
+			},
+			wantErr: true,
+		},
+		{
+			name: "all zones with count",
+			args: args{
+				c: NewStaticReply([]string{"ok, 3 zones"}),
+			},
+			want: &ZoneOperationResult{
+				All:    true,
+				Failed: map[string]string{},
+			},
+			wantErr: false,
+		},
+		{
+			name: "zone not secondary",
+			args: args{
+				c:     NewStaticReply([]string{"error zone not secondary"}),
+				zones: []string{"example.com"},
+			},
+			want: &ZoneOperationResult{
+				Failed: map[string]string{
+					"example.com": "error zone not secondary",
+				},
+			},
+			wantErr: true,
+		},
+		{
+			name: "malformed",
+			args: args{
+				c: NewStaticReply([]string{"asdf"}),
+			},
+			want:    nil,
+			wantErr: true,
+		},
+		{
+			name: "empty",
+			args: args{
+				c: NewStaticReply([]string{}),
+			},
+			want:    nil,
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parseZoneOperationReply(tt.args.c, tt.args.zones)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("parseZoneOperationReply() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("parseZoneOperationReply() got = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestClient_Reload(t *testing.T) {
+	// NSD accepts a single zone per command, "reload a b" fails with "error zone a b not configured"
+	c := New(pipeDialer(map[string]string{
+		cmdReload:                  "ok\n",
+		cmdReload + " example.com": "ok\n",
+		cmdReload + " example.net": "error zone example.net not configured\n",
+		cmdReload + " example.org": "ok\n",
+	}))
+
+	got, err := c.Reload([]string{"example.com", "example.net", "example.org"})
+	want := &ZoneOperationResult{
+		Succeeded: []string{"example.com", "example.org"},
+		Failed: map[string]string{
+			"example.net": "error zone example.net not configured",
+		},
+	}
+	if !errors.Is(err, ErrZoneNotFound) {
+		t.Errorf("Reload() error = %v, want %v", err, ErrZoneNotFound)
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Reload() got = %+v, want %+v", got, want)
+	}
+
+	if got, err := c.Reload(nil); err != nil || !got.All {
+		t.Errorf("Reload() = %+v, %v", got, err)
+	}
+	if _, err := c.Reload([]string{"example.com example.net"}); err == nil {
+		t.Error("Reload() expected error for a zone with a space")
+	}
+}
+
+func TestClient_zoneOperationSingleConnection(t *testing.T) {
+	clientConn, serverConn := net.Pipe()
+	defer func() { _ = serverConn.Close() }()
+	received := make(chan string, 1)
+	go func() {
+		scanner := bufio.NewScanner(serverConn)
+		scanner.Scan()
+		if scanner.Scan() {
+			received <- scanner.Text()
+		}
+		close(received)
+	}()
+
+	c := &Client{socket: clientConn}
+	if err := c.init(); err != nil {
+		t.Fatal(err)
+	}
+	zones := []string{"example.com", "example.org"}
+	for name, operation := range map[string]func([]string) (*ZoneOperationResult, error){
+		"Reload":        c.Reload,
+		"Write":         c.Write,
+		"Notify":        c.Notify,
+		"Transfer":      c.Transfer,
+		"ForceTransfer": c.ForceTransfer,
+	} {
+		// NSD closes the connection after the first zone, so nothing is sent
+		if _, err := operation(zones); !errors.Is(err, ErrSingleConnection) {
+			t.Errorf("%s() error = %v, want %v", name, err, ErrSingleConnection)
+		}
+	}
+	_ = clientConn.Close()
+	if cmd, ok := <-received; ok {
+		t.Errorf("sent %q", cmd)
+	}
+}
+
+func TestClient_ServerPID(t *testing.T) {
+	tests := []struct {
+		reply   string
+		want    int
+		wantErr bool
+	}{
+		{reply: "1234\n", want: 1234},
+		{reply: "error unknown command\n", want: -1, wantErr: true},
+		{reply: "pid\n", want: -1, wantErr: true},
+		// The whole reply is read, so a line after the process id is not ignored
+		{reply: "1234\nversion: 4.11.0\n", want: -1, wantErr: true},
+	}
+	for _, tt := range tests {
+		c := New(pipeDialer(map[string]string{cmdServerPID: tt.reply}))
+		if got, err := c.ServerPID(); got != tt.want || (err != nil) != tt.wantErr {
+			t.Errorf("ServerPID() for %q = %v, %v", tt.reply, got, err)
+		}
+	}
+}
+
+func Test_parseReconfigReply(t *testing.T) {
+	type args struct {
+		c replyReader
+	}
+	tests := []struct {
+		name    string
+		args    args
+		want    *ReconfigReport
+		wantErr bool
+	}{
+		{
+			name: "success",
+			args: args{
+				NewStaticReply([]string{
+					"reconfig start, read /etc/nsd/nsd.conf",
+					"ok",
+				}),
+			},
+			want: &ReconfigReport{
+				OK:         true,
+				ConfigFile: "/etc/nsd/nsd.conf",
+			},
+			wantErr: false,
+		},
+		{
+			name: "config error",
+			args: args{
+				NewStaticReply([]string{
+					"reconfig start, read /etc/nsd/nsd.conf",
+					"/etc/nsd/nsd.conf:12: error: syntax error",
+					"read /etc/nsd/nsd.conf failed: 1 errors in configuration file",
+				}),
+			},
+			want: &ReconfigReport{
+				OK:         false,
+				ConfigFile: "/etc/nsd/nsd.conf",
+				Errors: []string{
+					"/etc/nsd/nsd.conf:12: error: syntax error",
+					"read /etc/nsd/nsd.conf failed: 1 errors in configuration file",
+				},
+			},
+			wantErr: true,
+		},
+		{
+			name: "no reply",
+			args: args{
+				NewStaticReply([]string{}),
+			},
+			want:    nil,
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parseReconfigReply(tt.args.c)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("parseReconfigReply() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("parseReconfigReply() got = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_parseBulkZoneReply(t *testing.T) {
+	type args struct {
+		c     replyReader
+		zones []string
+		lines []string
+	}
+	tests := []struct {
+		name    string
+		args    args
+		want    *ZoneOperationResult
+		wantErr bool
+	}{
+		{
+			name: "all added",
+			args: args{
+				c:     NewStaticReply([]string{"added: 2"}),
+				zones: []string{"example.com", "example.net"},
+				lines: []string{"example.com replica", "example.net replica"},
+			},
+			want: &ZoneOperationResult{
+				Succeeded: []string{"example.com", "example.net"},
+				Failed:    map[string]string{},
+			},
+			wantErr: false,
+		},
+		{
+			name: "zone exists",
+			args: args{
+				c: NewStaticReply([]string{
+					"zone example.com already exists",
+					"error for input line 'example.com. replica'",
+					"added: 1",
+				}),
+				zones: []string{"example.com", "example.net"},
+				lines: []string{"example.com. replica", "example.net replica"},
+			},
+			want: &ZoneOperationResult{
+				Succeeded: []string{"example.net"},
+				Failed: map[string]string{
+					"example.com": "zone example.com already exists; error for input line 'example.com. replica'",
+				},
+			},
+			wantErr: true,
+		},
+		{
+			name: "pattern does not exist",
+			args: args{
+				c:     NewStaticReply([]string{"error pattern nope does not exist", "added: 1"}),
+				zones: []string{"a.example", "b.example"},
+				lines: []string{"a.example nope", "b.example replica"},
+			},
+			want: &ZoneOperationResult{
+				Succeeded: []string{"b.example"},
+				Failed: map[string]string{
+					"a.example": "error pattern nope does not exist",
+				},
+			},
+			wantErr: true,
+		},
+		{
+			name: "lines echoed",
+			args: args{
+				c: NewStaticReply([]string{
+					"added: a.example replica",
+					"error pattern nope does not exist",
+					"error for input line 'b.example nope'",
+					"added 1 zones",
+				}),
+				zones: []string{"a.example", "b.example"},
+				lines: []string{"a.example replica", "b.example nope"},
+			},
+			want: &ZoneOperationResult{
+				Succeeded: []string{"a.example"},
+				Failed: map[string]string{
+					"b.example": "error pattern nope does not exist; error for input line 'b.example nope'",
+				},
+			},
+			wantErr: true,
+		},
+		{
+			name: "unmatched error resolved by the summary",
+			args: args{
+				c: NewStaticReply([]string{
+					"error zone a.example already exists",
+					"error could not write zone list",
+					"error zone c.example already exists",
+					"added: 1",
+				}),
+				zones: []string{"a.example", "b.example", "c.example"},
+				lines: []string{"a.example replica", "b.example replica", "c.example replica"},
+			},
+			// The summary counts b.example, so the unmatched error is another error of a.example or c.example
+			want: &ZoneOperationResult{
+				Succeeded: []string{"b.example"},
+				Failed: map[string]string{
+					"a.example": "error zone a.example already exists",
+					"c.example": "error zone c.example already exists",
+				},
+			},
+			wantErr: true,
+		},
+		{
+			name: "unmatched error",
+			args: args{
+				c:     NewStaticReply([]string{"error out of memory", "added: 1"}),
+				zones: []string{"a.example", "b.example"},
+				lines: []string{"a.example replica", "b.example replica"},
+			},
+			// Either zone may have failed, so neither is reported as succeeded
+			want: &ZoneOperationResult{
+				Failed: map[string]string{},
+			},
+			wantErr: true,
+		},
+		{
+			name: "unmatched error failing all zones",
+			args: args{
+				c:     NewStaticReply([]string{"error out of memory", "added: 0"}),
+				zones: []string{"a.example", "b.example"},
+				lines: []string{"a.example replica", "b.example replica"},
+			},
+			want: &ZoneOperationResult{
+				Failed: map[string]string{
+					"a.example": "error out of memory",
+					"b.example": "error out of memory",
+				},
+			},
+			wantErr: true,
+		},
+		{
+			name: "missing summary",
+			args: args{
+				c:     NewStaticReply([]string{}),
+				zones: []string{"example.com"},
+				lines: []string{"example.com replica"},
+			},
+			want:    nil,
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parseBulkZoneReply(tt.args.c, tt.args.zones, tt.args.lines)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("parseBulkZoneReply() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("parseBulkZoneReply() got = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestClient_AddZones(t *testing.T) {
+	clientConn, serverConn := net.Pipe()
+	defer func() { _ = serverConn.Close() }()
+
+	// Reply to every line before reading the next, like NSD does,
+	// which deadlocks on an unbuffered pipe unless the client reads while sending
+	go func() {
+		scanner := bufio.NewScanner(serverConn)
+		scanner.Scan()
+		added := 0
+		for scanner.Scan() {
+			line := scanner.Text()
+			if line == endOfTransmission {
+				break
+			}
+			zone := strings.Fields(line)[0]
+			if zone == "example.net" {
+				_, _ = fmt.Fprintf(serverConn, "error zone %s already exists\n", zone)
+			} else {
+				added++
+			}
+		}
+		_, _ = fmt.Fprintf(serverConn, "added: %d\n\n", added)
+	}()
+
+	c := &Client{socket: clientConn}
+	if err := c.init(); err != nil {
+		t.Fatal(err)
+	}
+	var zones []ZonePattern
+	for i := 0; i < 1000; i++ {
+		zones = append(zones, ZonePattern{Zone: fmt.Sprintf("zone%d.example.com", i), Pattern: "replica"})
+	}
+	zones = append(zones, ZonePattern{Zone: "example.net", Pattern: "replica"})
+
+	got, err := c.AddZones(zones)
+	if err == nil {
+		t.Errorf("AddZones() expected error for existing zone")
+	}
+	if got == nil || len(got.Succeeded) != 1000 || got.Failed["example.net"] == "" {
+		t.Errorf("AddZones() got = %+v", got)
+	}
+}
+
+func Test_parseZoneStatuses(t *testing.T) {
+	reply := strings.Split(`zone:	example.com
+	state: primary
+zone:	example.dk.
+	pattern: replica
+	state: primary
+zone:	example.org
+	state: refreshing
+	served-serial: none
+	commit-serial: none
+	wait: "99 sec between attempts"`, "\n")
+	type args struct {
+		c       replyReader
+		filters []ZoneStatusFilter
+	}
+	tests := []struct {
+		name    string
+		args    args
+		want    []string
+		wantErr bool
+	}{
+		{
+			name: "all zones",
+			args: args{
+				c: NewStaticReply(reply),
+			},
+			want:    []string{"example.com", "example.dk.", "example.org"},
+			wantErr: false,
+		},
+		{
+			name: "state filter",
+			args: args{
+				c:       NewStaticReply(reply),
+				filters: []ZoneStatusFilter{ZoneStateFilter("refreshing")},
+			},
+			want:    []string{"example.org"},
+			wantErr: false,
+		},
+		{
+			name: "pattern filter",
+			args: args{
+				c:       NewStaticReply(reply),
+				filters: []ZoneStatusFilter{ZonePatternFilter("replica")},
+			},
+			want:    []string{"example.dk."},
+			wantErr: false,
+		},
+		{
+			name: "missing state",
+			args: args{
+				c: NewStaticReply(strings.Split(`zone:	example.com
+zone:	example.org
+	state: primary`, "\n")),
+			},
+			want:    nil,
+			wantErr: true,
+		},
+		{
+			name: "zone not configured",
+			args: args{
+				c: NewStaticReply([]string{"error zone example.net not configured"}),
+			},
+			want:    nil,
+			wantErr: true,
+		},
+		{
+			name: "no zones",
+			args: args{
+				c: NewStaticReply([]string{}),
+			},
+			want:    nil,
+			wantErr: false,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parseZoneStatuses(tt.args.c, tt.args.filters...)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("parseZoneStatuses() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			var zones []string
+			for _, status := range got {
+				zones = append(zones, status.Zone)
+			}
+			if !reflect.DeepEqual(zones, tt.want) {
+				t.Errorf("parseZoneStatuses() got = %v, want %v", zones, tt.want)
+			}
+		})
+	}
+}
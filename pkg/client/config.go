@@ -0,0 +1,227 @@
+package client
+
+import (
+	"bufio"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// Defaults of the remote-control clause, https://github.com/NLnetLabs/nsd/blob/NSD_4_11_0_REL/nsd.conf.5.in
+const (
+	DefaultConfigFile      = "/etc/nsd/nsd.conf"
+	DefaultControlPort     = 8952
+	DefaultServerCertFile  = "/etc/nsd/nsd_server.pem"
+	DefaultControlKeyFile  = "/etc/nsd/nsd_control.key"
+	DefaultControlCertFile = "/etc/nsd/nsd_control.pem"
+)
+
+// ErrControlDisabled is returned when creating a client from a config without control-enable
+var ErrControlDisabled = errors.New("remote control is disabled, control-enable is not set in the config file")
+
+// RemoteControlConfig is the remote-control clause of nsd.conf
+type RemoteControlConfig struct {
+	Enable bool
+	// Interfaces are the addresses or unix socket paths NSD listens on for control connections
+	Interfaces      []string
+	Port            int
+	ServerCertFile  string
+	ControlKeyFile  string
+	ControlCertFile string
+}
+
+// ReadRemoteControlConfig reads the remote-control clause of a nsd.conf file, following include directives
+func ReadRemoteControlConfig(path string) (*RemoteControlConfig, error) {
+	config := &RemoteControlConfig{
+		Port:            DefaultControlPort,
+		ServerCertFile:  DefaultServerCertFile,
+		ControlKeyFile:  DefaultControlKeyFile,
+		ControlCertFile: DefaultControlCertFile,
+	}
+	if _, err := config.read(path, "", 0); err != nil {
+		return nil, err
+	}
+	if len(config.Interfaces) == 0 {
+		config.Interfaces = []string{"127.0.0.1", "::1"}
+	}
+	return config, nil
+}
+
+// maxIncludeDepth guards against include loops
+const maxIncludeDepth = 16
+
+// read reads path starting in clause, and returns the clause in effect at its end.
+// Like in NSD includes are textual, so an included file continues the clause of the including file and vice versa.
+func (c *RemoteControlConfig) read(path string, clause string, depth int) (string, error) {
+	if depth > maxIncludeDepth {
+		return "", fmt.Errorf("%s: too many nested includes", path)
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer func() { _ = f.Close() }()
+
+	scanner := bufio.NewScanner(f)
+	for lineNo := 1; scanner.Scan(); lineNo++ {
+		line := strings.TrimSpace(stripConfigComment(scanner.Text()))
+		if line == "" {
+			continue
+		}
+		key, value, found := strings.Cut(line, ":")
+		if !found {
+			return "", fmt.Errorf("%s:%d: syntax error: %s", path, lineNo, line)
+		}
+		key = strings.TrimSpace(key)
+		value = strings.Trim(strings.TrimSpace(value), `"`)
+
+		if key == "include" {
+			// Includes may be glob patterns
+			matches, err := filepath.Glob(value)
+			if err != nil {
+				return "", fmt.Errorf("%s:%d: %w", path, lineNo, err)
+			}
+			for _, match := range matches {
+				if clause, err = c.read(match, clause, depth+1); err != nil {
+					return "", err
+				}
+			}
+			continue
+		}
+		if value == "" {
+			// Clauses such as "server:" and "remote-control:" have no value
+			clause = key
+			continue
+		}
+		if clause != "remote-control" {
+			continue
+		}
+
+		switch key {
+		case "control-enable":
+			c.Enable = value == "yes"
+		case "control-interface":
+			c.Interfaces = append(c.Interfaces, value)
+		case "control-port":
+			if c.Port, err = strconv.Atoi(value); err != nil {
+				return "", fmt.Errorf("%s:%d: invalid control-port: %s", path, lineNo, value)
+			}
+		case "server-cert-file":
+			c.ServerCertFile = value
+		case "control-key-file":
+			c.ControlKeyFile = value
+		case "control-cert-file":
+			c.ControlCertFile = value
+		}
+	}
+	return clause, scanner.Err()
+}
+
+// stripConfigComment removes a # comment that is not inside quotes
+func stripConfigComment(line string) string {
+	quoted := false
+	for i, r := range line {
+		switch r {
+		case '"':
+			quoted = !quoted
+		case '#':
+			if !quoted {
+				return line[:i]
+			}
+		}
+	}
+	return line
+}
+
+// NewClientFromConfig creates a dialing client from the remote-control clause of a nsd.conf file,
+// connecting to the first control-interface like nsd-control does.
+// Interfaces starting with / are unix sockets, others are connected to with TLS using the server and control certificates.
+func NewClientFromConfig(path string) (*Client, error) {
+	config, err := ReadRemoteControlConfig(path)
+	if err != nil {
+		return nil, err
+	}
+	return config.NewClient()
+}
+
+// NewClient creates a dialing client for the first control interface
+func (c *RemoteControlConfig) NewClient() (*Client, error) {
+	if !c.Enable {
+		return nil, ErrControlDisabled
+	}
+
+	iface := c.Interfaces[0]
+	if strings.HasPrefix(iface, "/") {
+		return NewDialingUNIXSocketClient(iface), nil
+	}
+
+	tlsConfig, err := c.TLSConfig()
+	if err != nil {
+		return nil, err
+	}
+	addr, err := net.ResolveTCPAddr("tcp", c.address(iface))
+	if err != nil {
+		return nil, err
+	}
+	return NewDialingTLSClient(addr, tlsConfig), nil
+}
+
+// address returns host:port for an interface, which may specify a port as ip@port
+func (c *RemoteControlConfig) address(iface string) string {
+	port := strconv.Itoa(c.Port)
+	if host, p, found := strings.Cut(iface, "@"); found {
+		iface, port = host, p
+	}
+	// NSD listens on all addresses, connect to the loopback address like nsd-control does
+	switch iface {
+	case "0.0.0.0":
+		iface = "127.0.0.1"
+	case "::0", "::":
+		iface = "::1"
+	}
+	return net.JoinHostPort(iface, port)
+}
+
+// TLSConfig loads the certificates for connecting to NSD.
+// Like nsd-control the server certificate is verified against server-cert-file, but its host name is not checked,
+// as the certificates generated by nsd-control-setup are issued to "nsd".
+func (c *RemoteControlConfig) TLSConfig() (*tls.Config, error) {
+	serverCert, err := os.ReadFile(c.ServerCertFile)
+	if err != nil {
+		return nil, err
+	}
+	roots := x509.NewCertPool()
+	if !roots.AppendCertsFromPEM(serverCert) {
+		return nil, fmt.Errorf("%s: no certificates found", c.ServerCertFile)
+	}
+	clientCert, err := tls.LoadX509KeyPair(c.ControlCertFile, c.ControlKeyFile)
+	if err != nil {
+		return nil, err
+	}
+
+	return &tls.Config{
+		Certificates: []tls.Certificate{clientCert},
+		// Verification is done by VerifyConnection without the host name
+		InsecureSkipVerify: true,
+		VerifyConnection: func(state tls.ConnectionState) error {
+			if len(state.PeerCertificates) == 0 {
+				return fmt.Errorf("server sent no certificate")
+			}
+			intermediates := x509.NewCertPool()
+			for _, cert := range state.PeerCertificates[1:] {
+				intermediates.AddCert(cert)
+			}
+			_, err := state.PeerCertificates[0].Verify(x509.VerifyOptions{
+				Roots:         roots,
+				Intermediates: intermediates,
+			})
+			return err
+		},
+	}, nil
+}
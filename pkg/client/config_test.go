@@ -0,0 +1,199 @@
+package client
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"fmt"
+	"math/big"
+	"net"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestReadRemoteControlConfig(t *testing.T) {
+	dir := t.TempDir()
+	include := filepath.Join(dir, "remote.conf")
+	writeFile(t, include, `remote-control:
+	control-key-file: "/etc/nsd/ctl.key" # comment
+	control-cert-file: /etc/nsd/ctl.pem
+`)
+	path := filepath.Join(dir, "nsd.conf")
+	writeFile(t, path, fmt.Sprintf(`server:
+	verbosity: 1
+	# control-port: 1
+
+remote-control:
+	control-enable: yes
+	control-interface: /run/nsd/nsd.sock
+	control-interface: 127.0.0.1@8953
+	control-port: 8954
+	server-cert-file: "/etc/nsd/server # 1.pem"
+
+zone:
+	name: example.com
+	control-port: 1
+
+include: %q
+`, include))
+
+	got, err := ReadRemoteControlConfig(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := &RemoteControlConfig{
+		Enable:          true,
+		Interfaces:      []string{"/run/nsd/nsd.sock", "127.0.0.1@8953"},
+		Port:            8954,
+		ServerCertFile:  "/etc/nsd/server # 1.pem",
+		ControlKeyFile:  "/etc/nsd/ctl.key",
+		ControlCertFile: "/etc/nsd/ctl.pem",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("ReadRemoteControlConfig() got = %+v, want %+v", got, want)
+	}
+	if addr := got.address(got.Interfaces[1]); addr != "127.0.0.1:8953" {
+		t.Errorf("address() = %s", addr)
+	}
+
+	// The config used by the docker setup in test/
+	got, err = ReadRemoteControlConfig("../../test/config/nsd.conf")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !got.Enable || got.Port != DefaultControlPort || got.Interfaces[0] != "0.0.0.0" || got.ControlKeyFile != "/etc/nsd/domain.key" {
+		t.Errorf("ReadRemoteControlConfig() got = %+v", got)
+	}
+	if addr := got.address(got.Interfaces[0]); addr != "127.0.0.1:8952" {
+		t.Errorf("address() = %s", addr)
+	}
+}
+
+func TestReadRemoteControlConfig_includeInClause(t *testing.T) {
+	dir := t.TempDir()
+	// Includes are textual, the included file continues the remote-control clause and ends in a zone clause
+	include := filepath.Join(dir, "remote.conf")
+	writeFile(t, include, `	control-enable: yes
+	control-port: 8954
+
+zone:
+	name: example.com
+`)
+	path := filepath.Join(dir, "nsd.conf")
+	writeFile(t, path, fmt.Sprintf(`remote-control:
+	include: %q
+	control-interface: 127.0.0.1
+`, include))
+
+	got, err := ReadRemoteControlConfig(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := &RemoteControlConfig{
+		Enable: true,
+		// control-interface follows the zone clause started by the include, so the default interfaces are used
+		Interfaces:      []string{"127.0.0.1", "::1"},
+		Port:            8954,
+		ServerCertFile:  DefaultServerCertFile,
+		ControlKeyFile:  DefaultControlKeyFile,
+		ControlCertFile: DefaultControlCertFile,
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("ReadRemoteControlConfig() got = %+v, want %+v", got, want)
+	}
+}
+
+func TestNewClientFromConfig(t *testing.T) {
+	dir := t.TempDir()
+	// Certificates are issued to "nsd" without SAN like the ones made by nsd-control-setup
+	serverCert := writeSelfSignedCert(t, dir, "nsd_server")
+	controlCert := writeSelfSignedCert(t, dir, "nsd_control")
+
+	controlPool := x509.NewCertPool()
+	controlPool.AddCert(controlCert.Leaf)
+	l, err := tls.Listen("tcp", "127.0.0.1:0", &tls.Config{
+		Certificates: []tls.Certificate{serverCert},
+		ClientAuth:   tls.RequireAndVerifyClientCert,
+		ClientCAs:    controlPool,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = l.Close() })
+	serveOneCommandPerConn(t, l, map[string]string{cmdServerPID: "1234\n"})
+
+	path := filepath.Join(dir, "nsd.conf")
+	writeFile(t, path, fmt.Sprintf(`remote-control:
+	control-enable: yes
+	control-interface: 0.0.0.0
+	control-port: %d
+	server-cert-file: %s
+	control-key-file: %s
+	control-cert-file: %s
+`, l.Addr().(*net.TCPAddr).Port, filepath.Join(dir, "nsd_server.pem"), filepath.Join(dir, "nsd_control.key"), filepath.Join(dir, "nsd_control.pem")))
+
+	c, err := NewClientFromConfig(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if pid, err := c.ServerPID(); err != nil || pid != 1234 {
+		t.Errorf("ServerPID() = %v, %v", pid, err)
+	}
+
+	writeFile(t, path, "remote-control:\n\tcontrol-enable: no\n")
+	if _, err := NewClientFromConfig(path); err != ErrControlDisabled {
+		t.Errorf("NewClientFromConfig() error = %v, want %v", err, ErrControlDisabled)
+	}
+}
+
+func writeFile(t *testing.T, path string, content string) {
+	t.Helper()
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// writeSelfSignedCert writes name.pem and name.key to dir
+func writeSelfSignedCert(t *testing.T, dir string, name string) tls.Certificate {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "nsd"},
+		DNSNames:              []string{"nsd"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		KeyUsage:              x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth},
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyDer, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	certPEM := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})
+	keyPEM := pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDer})
+	writeFile(t, filepath.Join(dir, name+".pem"), string(certPEM))
+	writeFile(t, filepath.Join(dir, name+".key"), string(keyPEM))
+
+	cert, err := tls.X509KeyPair(certPEM, keyPEM)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return cert
+}
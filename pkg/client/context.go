@@ -0,0 +1,139 @@
+package client
+
+import (
+	"context"
+	"io"
+	"time"
+)
+
+const (
+	// DefaultDialTimeout limits connecting to the server when the context has no deadline
+	DefaultDialTimeout = 10 * time.Second
+	// DefaultTimeout limits each command when the context has no deadline
+	DefaultTimeout = 30 * time.Second
+)
+
+// newClient performs the protocol handshake on conn, closing it on failure
+func newClient(ctx context.Context, conn io.ReadWriteCloser) (_ *Client, err error) {
+	client := &Client{
+		socket:  conn,
+		timeout: DefaultTimeout,
+	}
+	if _, err := client.begin(ctx); err != nil {
+		_ = conn.Close()
+		return nil, err
+	}
+	defer client.end(ctx, &err)
+
+	if err := client.init(); err != nil {
+		_ = conn.Close()
+		return nil, err
+	}
+	return client, nil
+}
+
+// deadlineConn is implemented by connections supporting deadlines, such as net.Conn
+type deadlineConn interface {
+	SetDeadline(t time.Time) error
+}
+
+// SetTimeout changes the deadline applied to commands when the context has no deadline, zero disables it.
+// It must not be called concurrently with commands.
+func (c *Client) SetTimeout(timeout time.Duration) {
+	c.timeout = timeout
+}
+
+// begin returns the client to run a command on, which is c itself for clients with a single connection,
+// and a client with a new handshaked connection for dialing and pooled clients.
+// The deadline of ctx is applied to the connection, and the command is interrupted if ctx is done.
+// If the connection does not support deadlines it is closed to interrupt the command.
+// Every successful call must be followed by a call to end on the returned client.
+func (c *Client) begin(ctx context.Context) (*Client, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	start := time.Now()
+	cmd := c
+	if c.pool != nil {
+		var err error
+		if cmd, err = c.pool.get(ctx, c.timeout); err != nil {
+			c.traceConnectError(ctx, start, err)
+			return nil, err
+		}
+	} else if c.dial != nil {
+		conn, err := c.dial(ctx)
+		if err != nil {
+			c.traceConnectError(ctx, start, err)
+			return nil, err
+		}
+		if cmd, err = newClient(ctx, conn); err != nil {
+			c.traceConnectError(ctx, start, err)
+			return nil, err
+		}
+		cmd.done = func() {}
+	} else {
+		c.mu.Lock()
+	}
+	cmd.timeout = c.timeout
+	cmd.logger = c.logger
+	cmd.observer = c.observer
+	cmd.traceStart = start
+
+	conn, hasDeadline := cmd.socket.(deadlineConn)
+	if hasDeadline {
+		deadline, ok := ctx.Deadline()
+		if !ok && cmd.timeout > 0 {
+			deadline = time.Now().Add(cmd.timeout)
+		}
+		if err := conn.SetDeadline(deadline); err != nil {
+			cmd.release()
+			return nil, err
+		}
+	}
+
+	interrupted := make(chan struct{})
+	cmd.interrupted = interrupted
+	cmd.stopInterrupt = context.AfterFunc(ctx, func() {
+		defer close(interrupted)
+		if hasDeadline {
+			// A deadline in the past unblocks pending reads and writes
+			_ = conn.SetDeadline(time.Unix(1, 0))
+		} else {
+			_ = cmd.socket.Close()
+		}
+	})
+	return cmd, nil
+}
+
+// end undoes begin, and replaces *err with the context error if the command failed because ctx is done
+func (c *Client) end(ctx context.Context, err *error) {
+	if !c.stopInterrupt() {
+		// Wait for the interrupt to finish so it doesn't affect the next command
+		<-c.interrupted
+	}
+	if conn, ok := c.socket.(deadlineConn); ok && c.done == nil {
+		_ = conn.SetDeadline(time.Time{})
+	}
+	if *err != nil {
+		if ctxErr := ctx.Err(); ctxErr != nil {
+			*err = ctxErr
+		} else if deadline, ok := ctx.Deadline(); ok && !time.Now().Before(deadline) {
+			// The connection deadline can expire just before the context notices
+			*err = context.DeadlineExceeded
+		}
+	}
+	c.traceEnd(ctx, *err)
+	c.release()
+}
+
+// release ends the use of the connection by a command.
+// Connections of per-command clients are closed, NSD handles a single command per connection.
+func (c *Client) release() {
+	if c.done == nil {
+		c.mu.Unlock()
+		return
+	}
+	_ = c.socket.Close()
+	c.done()
+}
@@ -0,0 +1,62 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net"
+	"testing"
+	"time"
+)
+
+func newPipeClient(t *testing.T) (*Client, net.Conn) {
+	t.Helper()
+	clientConn, serverConn := net.Pipe()
+	t.Cleanup(func() {
+		_ = clientConn.Close()
+		_ = serverConn.Close()
+	})
+	// Consume everything the client sends, but never reply
+	go func() { _, _ = io.Copy(io.Discard, serverConn) }()
+
+	c, err := newClient(context.Background(), clientConn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return c, serverConn
+}
+
+func TestClient_contextDeadline(t *testing.T) {
+	c, _ := newPipeClient(t)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	if _, err := c.StatusContext(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("StatusContext() error = %v, want %v", err, context.DeadlineExceeded)
+	}
+}
+
+func TestClient_contextCancel(t *testing.T) {
+	c, _ := newPipeClient(t)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(50*time.Millisecond, cancel)
+	if err := c.StopContext(ctx); !errors.Is(err, context.Canceled) {
+		t.Errorf("StopContext() error = %v, want %v", err, context.Canceled)
+	}
+
+	// Already cancelled contexts fail before anything is sent
+	if _, err := c.ServerPIDContext(ctx); !errors.Is(err, context.Canceled) {
+		t.Errorf("ServerPIDContext() error = %v, want %v", err, context.Canceled)
+	}
+}
+
+func TestClient_defaultTimeout(t *testing.T) {
+	c, _ := newPipeClient(t)
+	c.SetTimeout(50 * time.Millisecond)
+
+	var netErr net.Error
+	if _, err := c.Stats(); !errors.As(err, &netErr) || !netErr.Timeout() {
+		t.Errorf("Stats() error = %v, want timeout", err)
+	}
+}
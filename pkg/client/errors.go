@@ -0,0 +1,101 @@
+package client
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+)
+
+// Sentinel errors classifying the error messages sent by the server, use errors.Is to test for them
+var (
+	ErrZoneNotFound         = errors.New("zone not found")
+	ErrZoneExists           = errors.New("zone already exists")
+	ErrPatternNotFound      = errors.New("pattern not found")
+	ErrKeyNotFound          = errors.New("tsig key not found")
+	ErrKeyExists            = errors.New("tsig key already exists")
+	ErrInvalidTsigSecret    = errors.New("invalid tsig secret")
+	ErrUnsupportedAlgorithm = errors.New("unsupported tsig algorithm")
+	ErrInvalidCookieSecret  = errors.New("invalid cookie secret")
+)
+
+// ErrSingleConnection is returned by operations sending a command per zone on a client with a single connection,
+// as NSD closes the connection after the first command. Use a dialing or pooled client instead.
+var ErrSingleConnection = errors.New("several zones need a client that reconnects for every command")
+
+// ServerError is an error message sent by the server
+type ServerError struct {
+	// Message is the raw error message
+	Message string
+	// Err is the sentinel error matching the message, nil if the message is not recognized
+	Err error
+}
+
+func (e *ServerError) Error() string {
+	return "server send error: " + e.Message
+}
+
+func (e *ServerError) Unwrap() error {
+	return e.Err
+}
+
+// ProtocolError is a reply that doesn't match the protocol or the format expected for the command
+type ProtocolError struct {
+	// Reply holds the unexpected lines
+	Reply []string
+	// Reason describes what was expected, empty if the reply was not expected at all
+	Reason string
+	// Err is the error that occurred while parsing the reply, if any
+	Err error
+}
+
+func (e *ProtocolError) Error() string {
+	switch {
+	case e.Reason != "" && e.Err != nil:
+		return fmt.Sprintf("malformed reply, %s: %v", e.Reason, e.Err)
+	case e.Reason != "":
+		return "malformed reply, " + e.Reason
+	default:
+		return fmt.Sprintf("unexpected reply: %s", e.Reply)
+	}
+}
+
+func (e *ProtocolError) Unwrap() error {
+	return e.Err
+}
+
+// serverErrorPatterns classify error messages, the first match wins.
+// Messages from https://github.com/NLnetLabs/nsd/blob/NSD_4_11_0_REL/remote.c
+var serverErrorPatterns = []struct {
+	pattern *regexp.Regexp
+	err     error
+}{
+	{regexp.MustCompile(`no such key|key:? \S+ does not exist`), ErrKeyNotFound},
+	{regexp.MustCompile(`key:? (?:with name:? )?\S+ already exist`), ErrKeyExists},
+	{regexp.MustCompile(`not in base64 format`), ErrInvalidTsigSecret},
+	{regexp.MustCompile(`unsupported algorithm`), ErrUnsupportedAlgorithm},
+	{regexp.MustCompile(`invalid cookie secret`), ErrInvalidCookieSecret},
+	{regexp.MustCompile(`pattern:? \S+ (?:does not exist|not found)`), ErrPatternNotFound},
+	{regexp.MustCompile(`zone:? \S+ (?:not configured|does not exist|is not present|not found)`), ErrZoneNotFound},
+	{regexp.MustCompile(`zone:? \S+ (?:already )?exists`), ErrZoneExists},
+}
+
+func newServerError(message string) *ServerError {
+	serverErr := &ServerError{Message: message}
+	for _, p := range serverErrorPatterns {
+		if p.pattern.MatchString(message) {
+			serverErr.Err = p.err
+			break
+		}
+	}
+	return serverErr
+}
+
+// unexpectedReply is a ProtocolError for a reply that was not expected at all
+func unexpectedReply(reply ...string) *ProtocolError {
+	return &ProtocolError{Reply: reply}
+}
+
+// malformedReply is a ProtocolError for a reply not in the expected format
+func malformedReply(reason string, err error, reply ...string) *ProtocolError {
+	return &ProtocolError{Reply: reply, Reason: reason, Err: err}
+}
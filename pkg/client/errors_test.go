@@ -0,0 +1,136 @@
+package client
+
+import (
+	"errors"
+	"testing"
+)
+
+func Test_newServerError(t *testing.T) {
+	tests := []struct {
+		message string
+		want    error
+	}{
+		{message: "error zone example.net not configured", want: ErrZoneNotFound},
+		{message: "error: zone: example.net does not exist", want: ErrZoneNotFound},
+		{message: "error zone example.com already exists", want: ErrZoneExists},
+		{message: "error pattern replica does not exist", want: ErrPatternNotFound},
+		{message: "error: no such key with name: test", want: ErrKeyNotFound},
+		{message: "error: key: test does not exist", want: ErrKeyNotFound},
+		{message: "error: key test already exists", want: ErrKeyExists},
+		{message: "error: the secret: abc is not in base64 format", want: ErrInvalidTsigSecret},
+		{message: "error: unsupported algorithm: hmac-foo", want: ErrUnsupportedAlgorithm},
+		{message: "invalid cookie secret: invalid argument length", want: ErrInvalidCookieSecret},
+		{message: "error zone not secondary", want: nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.message, func(t *testing.T) {
+			err := newServerError(tt.message)
+			if err.Message != tt.message {
+				t.Errorf("newServerError() Message = %q, want %q", err.Message, tt.message)
+			}
+			if err.Err != tt.want {
+				t.Errorf("newServerError() Err = %v, want %v", err.Err, tt.want)
+			}
+			if tt.want != nil && !errors.Is(err, tt.want) {
+				t.Errorf("errors.Is(%v, %v) = false", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestErrors_replyParsers(t *testing.T) {
+	tests := []struct {
+		name      string
+		parse     func(c replyReader) error
+		reply     []string
+		wantIs    error
+		wantProto bool
+	}{
+		{
+			name:   "expectOk",
+			parse:  expectOk,
+			reply:  []string{"error zone example.net already exists"},
+			wantIs: ErrZoneExists,
+		},
+		{
+			name: "parseZoneStatus",
+			parse: func(c replyReader) error {
+				_, err := parseZoneStatus(c)
+				return err
+			},
+			reply:  []string{"error zone example.net not configured"},
+			wantIs: ErrZoneNotFound,
+		},
+		{
+			name: "parseZoneStatus malformed",
+			parse: func(c replyReader) error {
+				_, err := parseZoneStatus(c)
+				return err
+			},
+			reply:     []string{"state: ok"},
+			wantProto: true,
+		},
+		{
+			name:   "parseAddCookieSecretReply",
+			parse:  parseAddCookieSecretReply,
+			reply:  []string{"invalid cookie secret: invalid argument length", "please provide a 128bit hex encoded secret"},
+			wantIs: ErrInvalidCookieSecret,
+		},
+		{
+			name: "parseCookieSecretsReply",
+			parse: func(c replyReader) error {
+				_, err := parseCookieSecretsReply(c)
+				return err
+			},
+			reply: []string{"error: cookie secrets are not supported"},
+		},
+		{
+			name: "parseStatsReply malformed",
+			parse: func(c replyReader) error {
+				_, err := parseStatsReply(c)
+				return err
+			},
+			reply:     []string{"num.queries=many"},
+			wantProto: true,
+		},
+		{
+			name: "Reload",
+			parse: func(c replyReader) error {
+				_, err := parseZoneOperationReply(c, []string{"example.net"})
+				return err
+			},
+			reply:  []string{"error zone example.net not configured"},
+			wantIs: ErrZoneNotFound,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.parse(NewStaticReply(tt.reply))
+			if err == nil {
+				t.Fatal("expected an error")
+			}
+			var serverErr *ServerError
+			var protoErr *ProtocolError
+			if tt.wantProto {
+				if !errors.As(err, &protoErr) {
+					t.Errorf("error %v is not a ProtocolError", err)
+				}
+				return
+			}
+			if !errors.As(err, &serverErr) {
+				t.Fatalf("error %v is not a ServerError", err)
+			}
+			if tt.wantIs != nil && !errors.Is(err, tt.wantIs) {
+				t.Errorf("errors.Is(%v, %v) = false", err, tt.wantIs)
+			}
+		})
+	}
+}
+
+func TestKeyInUseError_Unwrap(t *testing.T) {
+	err := expectTsigOk(NewStaticReply([]string{"error: key: test is in use by zone example.org"}), "test", "")
+	var serverErr *ServerError
+	if !errors.As(err, &serverErr) || serverErr.Message != "error: key: test is in use by zone example.org" {
+		t.Errorf("expectTsigOk() error = %v, want a ServerError with the raw message", err)
+	}
+}
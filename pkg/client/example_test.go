@@ -0,0 +1,44 @@
+package client_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"nsd/pkg/client"
+	"nsd/pkg/client/nsdtest"
+)
+
+func ExampleNew() {
+	server := nsdtest.NewUNIXServer(nil)
+	defer server.Close()
+
+	c := client.New(client.UNIXDialer(server.Addr().String()), client.WithRetryPolicy(&client.DefaultRetryPolicy))
+	status, err := c.ZoneStatus("example.org")
+	if err != nil {
+		panic(err)
+	}
+	fmt.Println(status.Zone, status.State, status.ServedSerial.Serial)
+
+	_, err = c.ZoneStatus("example.net")
+	fmt.Println(errors.Is(err, client.ErrZoneNotFound))
+	// Output:
+	// example.org ok 2024010100
+	// true
+}
+
+func ExampleClient_ZoneStatusesSeq() {
+	server := nsdtest.NewUNIXServer(nil)
+	defer server.Close()
+
+	c := server.Client()
+	for status, err := range c.ZoneStatusesSeq(context.Background()) {
+		if err != nil {
+			panic(err)
+		}
+		fmt.Println(status.Zone, status.State)
+	}
+	// Output:
+	// example.com primary
+	// example.org ok
+}
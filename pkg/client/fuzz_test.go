@@ -0,0 +1,390 @@
+package client
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"maps"
+	"reflect"
+	"slices"
+	"strconv"
+	"testing"
+	"time"
+)
+
+// The seed corpus of every target is in testdata/fuzz, run a target with e.g.
+//
+//	go test -fuzz=FuzzParseZoneStatuses ./pkg/client
+
+// fuzzReply reads data as a reply the same way it is read from a connection, which bounds lines to the scanner's buffer
+func fuzzReply(data []byte) *Client {
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	scanner.Split(scanReplyLines)
+	return &Client{scanner: scanner}
+}
+
+// maxFuzzLines is the number of reply lines data can contain, a bound for the number of parsed values
+func maxFuzzLines(data []byte) int {
+	return bytes.Count(data, []byte("\n")) + 1
+}
+
+// checkReplyError fails unless err is an error returned for replies the client does not understand
+func checkReplyError(t *testing.T, err error) {
+	t.Helper()
+	var serverErr *ServerError
+	var protocolErr *ProtocolError
+	if !errors.As(err, &serverErr) && !errors.As(err, &protocolErr) && !errors.Is(err, bufio.ErrTooLong) && !errors.Is(err, io.ErrUnexpectedEOF) {
+		t.Fatalf("unexpected error %T: %v", err, err)
+	}
+}
+
+// quoteValue formats a value the way commonKeyValueRegex parses it back unchanged
+func quoteValue(key string, value string) string {
+	return fmt.Sprintf("%s: \"%s\"", key, value)
+}
+
+func FuzzCommonKeyValueRegex(f *testing.F) {
+	f.Fuzz(func(t *testing.T, line string) {
+		match := commonKeyValueRegex.FindStringSubmatch(line)
+		if match == nil {
+			return
+		}
+		key := match[commonKeyValueRegex.SubexpIndex("key")]
+		value := match[commonKeyValueRegex.SubexpIndex("value")]
+
+		formatted := quoteValue(key, value)
+		match = commonKeyValueRegex.FindStringSubmatch(formatted)
+		if match == nil {
+			t.Fatalf("%q does not match", formatted)
+		}
+		if gotKey, gotValue := match[commonKeyValueRegex.SubexpIndex("key")], match[commonKeyValueRegex.SubexpIndex("value")]; gotKey != key || gotValue != value {
+			t.Errorf("%q parsed as %q: %q, want %q: %q", formatted, gotKey, gotValue, key, value)
+		}
+	})
+}
+
+func FuzzParseVersion(f *testing.F) {
+	f.Fuzz(func(t *testing.T, s string) {
+		v, err := ParseVersion(s)
+		if err != nil {
+			return
+		}
+		got, err := ParseVersion(v.String())
+		if err != nil {
+			t.Fatalf("ParseVersion(%q) error = %v", v.String(), err)
+		}
+		if got != v || got.Compare(v) != 0 {
+			t.Errorf("ParseVersion(%q) = %+v, want %+v", v.String(), got, v)
+		}
+	})
+}
+
+func FuzzParseStatusReply(f *testing.F) {
+	f.Fuzz(func(t *testing.T, data []byte) {
+		status, err := parseStatusReply(fuzzReply(data))
+		if err != nil {
+			checkReplyError(t, err)
+			return
+		}
+		if len(status.Other) > maxFuzzLines(data) {
+			t.Fatalf("%d other keys from %d lines", len(status.Other), maxFuzzLines(data))
+		}
+
+		reply := []string{
+			quoteValue("version", status.Version.String()),
+			quoteValue("verbosity", strconv.Itoa(status.Verbosity)),
+		}
+		if status.Ratelimit != nil {
+			reply = append(reply, quoteValue("ratelimit", strconv.Itoa(*status.Ratelimit)))
+		}
+		for _, key := range slices.Sorted(maps.Keys(status.Other)) {
+			reply = append(reply, quoteValue(key, status.Other[key]))
+		}
+		got, err := parseStatusReply(NewStaticReply(reply))
+		if err != nil {
+			t.Fatalf("parseStatusReply(%q) error = %v", reply, err)
+		}
+		if !reflect.DeepEqual(got, status) {
+			t.Errorf("parseStatusReply(%q) = %+v, want %+v", reply, got, status)
+		}
+	})
+}
+
+func FuzzParseStatsReply(f *testing.F) {
+	f.Fuzz(func(t *testing.T, data []byte) {
+		stats, err := parseStatsReply(fuzzReply(data))
+		if err != nil {
+			checkReplyError(t, err)
+			return
+		}
+		if len(stats.ServerQueries) > maxStatsServers {
+			t.Fatalf("%d server counters", len(stats.ServerQueries))
+		}
+		if n := len(stats.Other) + len(stats.QueryTypes) + len(stats.Opcodes) + len(stats.Classes) + len(stats.Rcodes); n > maxFuzzLines(data) {
+			t.Fatalf("%d keys from %d lines", n, maxFuzzLines(data))
+		}
+
+		var entries []StatsEntry
+		if err := statsSeq(fuzzReply(data).replyLines(), func(entry StatsEntry) bool {
+			entries = append(entries, entry)
+			return true
+		}); err != nil {
+			t.Fatalf("statsSeq() error = %v", err)
+		}
+		reply := make([]string, 0, len(entries))
+		for _, entry := range entries {
+			reply = append(reply, entry.Key+"="+entry.Value)
+		}
+		got, err := parseStatsReply(NewStaticReply(reply))
+		if err != nil {
+			t.Fatalf("parseStatsReply(%q) error = %v", reply, err)
+		}
+		if !reflect.DeepEqual(got, stats) {
+			t.Errorf("parseStatsReply(%q) = %+v, want %+v", reply, got, stats)
+		}
+	})
+}
+
+func FuzzParseStatsTime(f *testing.F) {
+	f.Fuzz(func(t *testing.T, value string) {
+		d, err := parseStatsTime(value)
+		if err != nil {
+			return
+		}
+		if d < 0 {
+			t.Fatalf("parseStatsTime(%q) = %v", value, d)
+		}
+		formatted := fmt.Sprintf("%d.%09d", d/time.Second, d%time.Second)
+		if got, err := parseStatsTime(formatted); err != nil || got != d {
+			t.Errorf("parseStatsTime(%q) = %v, %v, want %v", formatted, got, err, d)
+		}
+	})
+}
+
+// formatZoneStatus formats a zone the way zonestatus prints it
+func formatZoneStatus(status ZoneStatus) []string {
+	formatSerial := func(serial *ZoneSerial) string {
+		if serial.Since.IsZero() {
+			return strconv.FormatUint(uint64(serial.Serial), 10)
+		}
+		return fmt.Sprintf("%d since %s", serial.Serial, serial.Since.Format(zoneSerialTimeLayout))
+	}
+
+	reply := []string{quoteValue("zone", status.Zone)}
+	for _, attr := range []struct{ key, value string }{
+		{"pattern", status.Pattern},
+		{"catalog", status.Catalog},
+		{"catalog-member-id", status.CatalogMemberID},
+		{"state", string(status.State)},
+	} {
+		if attr.value != "" {
+			reply = append(reply, "\t"+quoteValue(attr.key, attr.value))
+		}
+	}
+	if status.ServedSerial != nil {
+		reply = append(reply, "\t"+quoteValue("served-serial", formatSerial(status.ServedSerial)))
+	}
+	if status.CommitSerial != nil {
+		reply = append(reply, "\t"+quoteValue("commit-serial", formatSerial(status.CommitSerial)))
+	}
+	if status.Wait != nil {
+		reply = append(reply, "\t"+quoteValue("wait", fmt.Sprintf("%d sec %s", status.Wait.Duration/time.Second, status.Wait.Reason)))
+	}
+	if transfer := status.Transfer; transfer != nil {
+		value := transfer.Status
+		if transfer.Address != "" || transfer.Status == "" {
+			value += " to " + transfer.Address
+		}
+		reply = append(reply, "\t"+quoteValue("transfer", value))
+	}
+	for _, key := range slices.Sorted(maps.Keys(status.Attributes)) {
+		reply = append(reply, "\t"+quoteValue(key, status.Attributes[key]))
+	}
+	return reply
+}
+
+func FuzzParseZoneStatuses(f *testing.F) {
+	f.Fuzz(func(t *testing.T, data []byte) {
+		if _, err := parseZoneStatus(fuzzReply(data)); err != nil {
+			checkReplyError(t, err)
+		}
+
+		statuses, err := parseZoneStatuses(fuzzReply(data))
+		if err != nil {
+			checkReplyError(t, err)
+			return
+		}
+		if len(statuses) > maxFuzzLines(data) {
+			t.Fatalf("%d zones from %d lines", len(statuses), maxFuzzLines(data))
+		}
+
+		var reply []string
+		for _, status := range statuses {
+			reply = append(reply, formatZoneStatus(status)...)
+		}
+		got, err := parseZoneStatuses(NewStaticReply(reply))
+		if err != nil {
+			t.Fatalf("parseZoneStatuses(%q) error = %v", reply, err)
+		}
+		if !reflect.DeepEqual(got, statuses) {
+			t.Errorf("parseZoneStatuses(%q) = %+v, want %+v", reply, got, statuses)
+		}
+	})
+}
+
+func FuzzParseTsigKeysReply(f *testing.F) {
+	f.Fuzz(func(t *testing.T, data []byte) {
+		if err := expectTsigOk(fuzzReply(data), "test", ""); err != nil {
+			checkReplyError(t, err)
+		}
+
+		keys, err := parseTsigKeysReply(fuzzReply(data))
+		if err != nil {
+			checkReplyError(t, err)
+			return
+		}
+		if len(keys) > maxFuzzLines(data) {
+			t.Fatalf("%d keys from %d lines", len(keys), maxFuzzLines(data))
+		}
+
+		reply := make([]string, 0, len(keys))
+		for _, key := range keys {
+			reply = append(reply, fmt.Sprintf(`key: name: "%s" secret: "%s" algorithm: %s`, key.Name, key.Secret, key.Algorithm))
+		}
+		got, err := parseTsigKeysReply(NewStaticReply(reply))
+		if err != nil {
+			t.Fatalf("parseTsigKeysReply(%q) error = %v", reply, err)
+		}
+		if !reflect.DeepEqual(got, keys) {
+			t.Errorf("parseTsigKeysReply(%q) = %+v, want %+v", reply, got, keys)
+		}
+	})
+}
+
+func FuzzParseCookieSecretsReply(f *testing.F) {
+	f.Fuzz(func(t *testing.T, data []byte) {
+		if err := parseAddCookieSecretReply(fuzzReply(data)); err != nil {
+			checkReplyError(t, err)
+		}
+
+		secrets, err := parseCookieSecretsReply(fuzzReply(data))
+		if err != nil {
+			checkReplyError(t, err)
+			return
+		}
+
+		var reply []string
+		if secrets.Source != "" {
+			reply = append(reply, quoteValue("source", secrets.Source))
+		}
+		if secrets.Active != "" {
+			reply = append(reply, quoteValue("active", secrets.Active))
+		}
+		if secrets.Staging != nil {
+			reply = append(reply, quoteValue("staging", *secrets.Staging))
+		}
+		got, err := parseCookieSecretsReply(NewStaticReply(reply))
+		if err != nil {
+			t.Fatalf("parseCookieSecretsReply(%q) error = %v", reply, err)
+		}
+		if !reflect.DeepEqual(got, secrets) {
+			t.Errorf("parseCookieSecretsReply(%q) = %+v, want %+v", reply, got, secrets)
+		}
+	})
+}
+
+func FuzzExpectOk(f *testing.F) {
+	f.Fuzz(func(t *testing.T, data []byte) {
+		err := expectOk(fuzzReply(data))
+		if err != nil {
+			checkReplyError(t, err)
+			return
+		}
+		if lines, _ := fuzzReply(data).readReply(); !slices.Equal(lines, []string{replyOK}) {
+			t.Errorf("expectOk(%q) accepted %q", data, lines)
+		}
+	})
+}
+
+func FuzzParseRawReply(f *testing.F) {
+	f.Fuzz(func(t *testing.T, data []byte) {
+		reply, err := parseRawReply(fuzzReply(data))
+		if reply == nil {
+			checkReplyError(t, err)
+			return
+		} else if err != nil {
+			checkReplyError(t, err)
+		}
+		if len(reply.Lines) > maxFuzzLines(data) {
+			t.Fatalf("%d lines from %d lines", len(reply.Lines), maxFuzzLines(data))
+		}
+		if reply.OK() && err != nil {
+			t.Errorf("OK() with error %v", err)
+		}
+		for key, value := range reply.KeyValues() {
+			if values := reply.Values(key); len(values) == 0 || values[len(values)-1] != value {
+				t.Errorf("Values(%q) = %q, want last value %q", key, values, value)
+			}
+		}
+	})
+}
+
+func FuzzParseReconfigReply(f *testing.F) {
+	f.Fuzz(func(t *testing.T, data []byte) {
+		report, err := parseReconfigReply(fuzzReply(data))
+		if report == nil {
+			checkReplyError(t, err)
+			return
+		} else if err != nil {
+			checkReplyError(t, err)
+		}
+
+		var reply []string
+		if report.ConfigFile != "" {
+			reply = append(reply, "reconfig start, read "+report.ConfigFile)
+		}
+		reply = append(reply, report.Errors...)
+		if report.OK {
+			reply = append(reply, replyOK)
+		}
+		got, _ := parseReconfigReply(NewStaticReply(reply))
+		if !reflect.DeepEqual(got, report) {
+			t.Errorf("parseReconfigReply(%q) = %+v, want %+v", reply, got, report)
+		}
+	})
+}
+
+func FuzzParseZoneOperationReply(f *testing.F) {
+	f.Fuzz(func(t *testing.T, data []byte) {
+		for _, zones := range [][]string{nil, {"example.com"}, {"example.com", "example.net"}} {
+			for name, parse := range map[string]func(replyReader, []string) (*ZoneOperationResult, error){
+				"parseZoneOperationReply": parseZoneOperationReply,
+				"parseBulkZoneReply": func(c replyReader, zones []string) (*ZoneOperationResult, error) {
+					lines := make([]string, len(zones))
+					for i, zone := range zones {
+						lines[i] = zone + " replica"
+					}
+					return parseBulkZoneReply(c, zones, lines)
+				},
+			} {
+				result, err := parse(fuzzReply(data), zones)
+				if err != nil {
+					checkReplyError(t, err)
+				}
+				if result == nil {
+					continue
+				}
+				if len(result.Succeeded) > len(zones) || len(result.Failed) > maxFuzzLines(data) {
+					t.Fatalf("%s(%q) = %+v", name, zones, result)
+				}
+				for _, zone := range result.Succeeded {
+					if !slices.Contains(zones, zone) || result.failed(zone) {
+						t.Errorf("%s(%q) succeeded for %q", name, zones, zone)
+					}
+				}
+			}
+		}
+	})
+}
@@ -0,0 +1,206 @@
+package nsdtest
+
+import (
+	"bufio"
+	"context"
+	"io"
+	"math/rand/v2"
+	"net"
+	"slices"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"syscall"
+	"time"
+
+	"nsd/pkg/client"
+)
+
+// Fault is a failure injected into a connection by ChaosDialer
+type Fault string
+
+const (
+	// FaultNone passes the reply through, delayed if Chaos.Latency is set
+	FaultNone Fault = ""
+	// FaultDisconnect resets the connection after a random part of the reply
+	FaultDisconnect Fault = "disconnect"
+	// FaultTruncate cuts a random line of the reply short and closes the connection
+	FaultTruncate Fault = "truncate"
+	// FaultUnterminated sends the reply without ending it, then stalls until the client closes the connection
+	FaultUnterminated Fault = "unterminated"
+	// FaultOversized makes a random line of the reply longer than bufio.Scanner reads by default
+	FaultOversized Fault = "oversized"
+	// FaultGarbage replaces a random line of the reply with random bytes
+	FaultGarbage Fault = "garbage"
+)
+
+// Chaos configures the faults injected by ChaosDialer.
+// Probabilities are per connection, and their sum should not exceed 1 as at most one fault is injected.
+type Chaos struct {
+	// Seed makes the faults deterministic for connections dialed in the same order
+	Seed uint64
+	// Latency is the maximum random delay before each line of the reply
+	Latency time.Duration
+
+	Disconnect   float64
+	Truncate     float64
+	Unterminated float64
+	Oversized    float64
+	Garbage      float64
+}
+
+// ChaosDialer is a client.Dialer injecting faults into the replies received over the connections of another dialer,
+// to test that code using the client copes with misbehaving servers and networks:
+//
+//	dialer := nsdtest.NewChaosDialer(server.Dialer(), nsdtest.Chaos{Seed: 1, Truncate: 0.1, Latency: time.Millisecond})
+//	c := client.New(dialer)
+type ChaosDialer struct {
+	dialer client.Dialer
+	chaos  Chaos
+
+	mu     sync.Mutex
+	rand   *rand.Rand
+	faults []Fault
+}
+
+// NewChaosDialer returns a dialer injecting faults into the connections of dialer
+func NewChaosDialer(dialer client.Dialer, chaos Chaos) *ChaosDialer {
+	return &ChaosDialer{
+		dialer: dialer,
+		chaos:  chaos,
+		rand:   rand.New(rand.NewPCG(chaos.Seed, 0)),
+	}
+}
+
+func (d *ChaosDialer) Dial(ctx context.Context) (io.ReadWriteCloser, error) {
+	server, err := d.dialer.Dial(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	d.mu.Lock()
+	fault := d.pick()
+	d.faults = append(d.faults, fault)
+	// Each connection has its own source, so concurrent connections don't change each other's faults
+	connRand := rand.New(rand.NewPCG(d.rand.Uint64(), d.rand.Uint64()))
+	d.mu.Unlock()
+
+	clientConn, proxyConn := net.Pipe()
+	conn := &chaosConn{Conn: clientConn}
+	go d.proxy(server, proxyConn, conn, fault, connRand)
+	return conn, nil
+}
+
+// Faults returns the fault injected into each connection, in the order they were dialed
+func (d *ChaosDialer) Faults() []Fault {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return slices.Clone(d.faults)
+}
+
+func (d *ChaosDialer) pick() Fault {
+	p := d.rand.Float64()
+	for _, f := range []struct {
+		fault       Fault
+		probability float64
+	}{
+		{FaultDisconnect, d.chaos.Disconnect},
+		{FaultTruncate, d.chaos.Truncate},
+		{FaultUnterminated, d.chaos.Unterminated},
+		{FaultOversized, d.chaos.Oversized},
+		{FaultGarbage, d.chaos.Garbage},
+	} {
+		if p < f.probability {
+			return f.fault
+		}
+		p -= f.probability
+	}
+	return FaultNone
+}
+
+// proxy forwards the command to the server, and its reply with the fault injected to the client
+func (d *ChaosDialer) proxy(server io.ReadWriteCloser, proxyConn net.Conn, conn *chaosConn, fault Fault, r *rand.Rand) {
+	defer func() { _ = proxyConn.Close() }()
+	clientClosed := make(chan struct{})
+	go func() {
+		defer close(clientClosed)
+		_, _ = io.Copy(server, proxyConn)
+		// Unblocks reading the reply if the client gave up
+		_ = server.Close()
+	}()
+
+	data, _ := io.ReadAll(server)
+	_ = server.Close()
+	lines := strings.SplitAfter(string(data), "\n")
+	if lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+	}
+
+	switch fault {
+	case FaultDisconnect:
+		cut := r.IntN(len(data) + 1)
+		_, _ = proxyConn.Write(data[:cut])
+		conn.reset.Store(true)
+		return
+	case FaultTruncate:
+		if len(lines) > 0 {
+			i := r.IntN(len(lines))
+			// At least a byte of the line is kept, a reply cut between lines looks complete
+			if line := strings.TrimSuffix(lines[i], "\n"); line != "" {
+				lines = append(lines[:i:i], line[:1+r.IntN(len(line))])
+			}
+		}
+	case FaultUnterminated:
+		// Without the terminating empty line or the end of the connection the client waits for more
+		for len(lines) > 0 && lines[len(lines)-1] == "\n" {
+			lines = lines[:len(lines)-1]
+		}
+	case FaultOversized:
+		i := r.IntN(len(lines) + 1)
+		line := strings.Repeat("x", bufio.MaxScanTokenSize) + "\n"
+		if i < len(lines) {
+			lines[i] = strings.TrimSuffix(lines[i], "\n") + line
+		} else {
+			lines = append(lines, line)
+		}
+	case FaultGarbage:
+		garbage := make([]byte, 1+r.IntN(80))
+		for j := range garbage {
+			if garbage[j] = byte(r.Uint32()); garbage[j] == '\n' {
+				garbage[j] = ' '
+			}
+		}
+		i := r.IntN(len(lines) + 1)
+		if i < len(lines) {
+			lines[i] = string(garbage) + "\n"
+		} else {
+			lines = append(lines, string(garbage)+"\n")
+		}
+	}
+
+	for _, line := range lines {
+		if d.chaos.Latency > 0 {
+			time.Sleep(time.Duration(r.Int64N(int64(d.chaos.Latency) + 1)))
+		}
+		if _, err := proxyConn.Write([]byte(line)); err != nil {
+			return
+		}
+	}
+	if fault == FaultUnterminated {
+		<-clientClosed
+	}
+}
+
+// chaosConn is the client side of a proxied connection, it reports a reset instead of the end of a disconnected reply
+type chaosConn struct {
+	net.Conn
+	reset atomic.Bool
+}
+
+func (c *chaosConn) Read(p []byte) (int, error) {
+	n, err := c.Conn.Read(p)
+	if err == io.EOF && c.reset.Load() {
+		err = &net.OpError{Op: "read", Net: "pipe", Err: syscall.ECONNRESET}
+	}
+	return n, err
+}
@@ -0,0 +1,35 @@
+package nsdtest
+
+import (
+	"context"
+	"slices"
+	"testing"
+)
+
+func TestChaosDialer_Faults(t *testing.T) {
+	server := NewUNIXServer(nil)
+	defer server.Close()
+
+	chaos := Chaos{Seed: 42, Disconnect: 0.2, Truncate: 0.2, Unterminated: 0.2, Oversized: 0.2, Garbage: 0.1}
+	dial := func() []Fault {
+		dialer := NewChaosDialer(server.Dialer(), chaos)
+		for range 50 {
+			conn, err := dialer.Dial(context.Background())
+			if err != nil {
+				t.Fatal(err)
+			}
+			_ = conn.Close()
+		}
+		return dialer.Faults()
+	}
+
+	faults := dial()
+	if again := dial(); !slices.Equal(faults, again) {
+		t.Errorf("Faults() = %v, then %v with the same seed", faults, again)
+	}
+	for _, fault := range []Fault{FaultNone, FaultDisconnect, FaultTruncate, FaultUnterminated, FaultOversized, FaultGarbage} {
+		if !slices.Contains(faults, fault) {
+			t.Errorf("Faults() = %v, missing %q", faults, fault)
+		}
+	}
+}
@@ -0,0 +1,411 @@
+package nsdtest
+
+import (
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"maps"
+	"slices"
+	"strconv"
+	"strings"
+
+	"nsd/pkg/client"
+)
+
+// builtinHandlers implement the commands listed in spec.go, with the replies of NSD 4.11.
+// NSD handlers: https://github.com/NLnetLabs/nsd/blob/NSD_4_11_0_REL/remote.c (execute_cmd)
+var builtinHandlers map[string]HandlerFunc
+
+func init() {
+	builtinHandlers = map[string]HandlerFunc{
+		"stop":                   handleStop,
+		"reload":                 zoneOperation(func(z *Zone) { z.Reloads++ }),
+		"write":                  zoneOperation(func(z *Zone) { z.Writes++ }),
+		"notify":                 zoneOperation(func(z *Zone) { z.Notifies++ }),
+		"transfer":               zoneOperation(func(z *Zone) { z.Transfers++ }),
+		"force_transfer":         zoneOperation(func(z *Zone) { z.Transfers++ }),
+		"reconfig":               handleReconfig,
+		"repattern":              handleReconfig,
+		"log_reopen":             handleLogReopen,
+		"status":                 handleStatus,
+		"stats":                  handleStats,
+		"stats_noreset":          handleStats,
+		"serverpid":              handleServerPID,
+		"verbosity":              handleVerbosity,
+		"addzone":                handleAddZone,
+		"delzone":                handleDelZone,
+		"changezone":             handleChangeZone,
+		"addzones":               handleAddZones,
+		"delzones":               handleDelZones,
+		"zonestatus":             handleZoneStatus,
+		"print_tsig":             handlePrintTsig,
+		"add_tsig":               handleAddTsig,
+		"update_tsig":            handleUpdateTsig,
+		"assoc_tsig":             handleAssocTsig,
+		"del_tsig":               handleDelTsig,
+		"add_cookie_secret":      handleAddCookieSecret,
+		"drop_cookie_secret":     handleDropCookieSecret,
+		"activate_cookie_secret": handleActivateCookieSecret,
+		"print_cookie_secrets":   handlePrintCookieSecrets,
+	}
+}
+
+var replyOK = []string{"ok"}
+
+func errorf(format string, args ...any) []string {
+	return []string{fmt.Sprintf(format, args...)}
+}
+
+func handleStop(state *State, req Request) []string {
+	state.Stopped = true
+	return replyOK
+}
+
+// zoneOperation applies f to the given zone, or to all zones without an argument
+func zoneOperation(f func(zone *Zone)) HandlerFunc {
+	return func(state *State, req Request) []string {
+		if len(req.Args) == 0 {
+			for _, zone := range state.Zones {
+				f(zone)
+			}
+			return replyOK
+		}
+		zone, reply := zoneArg(state, req)
+		if zone == nil {
+			return reply
+		}
+		f(zone)
+		return replyOK
+	}
+}
+
+// zoneArg returns the zone named by the argument of req, or the error reply.
+// Like get_zone_arg in remote.c the whole argument is the zone name, so several zones are rejected.
+func zoneArg(state *State, req Request) (*Zone, []string) {
+	if len(req.Args) > 1 {
+		return nil, errorf("error cannot parse zone name '%s'", strings.Join(req.Args, " "))
+	}
+	zone := state.Zone(req.Args[0])
+	if zone == nil {
+		return nil, errorf("error zone %s not configured", req.Args[0])
+	}
+	return zone, nil
+}
+
+func handleReconfig(state *State, req Request) []string {
+	reply := []string{"reconfig start, read " + state.ConfigFile}
+	if len(state.ConfigErrors) > 0 {
+		return append(reply, state.ConfigErrors...)
+	}
+	return append(reply, "ok")
+}
+
+func handleLogReopen(state *State, req Request) []string {
+	state.LogReopens++
+	return replyOK
+}
+
+func handleStatus(state *State, req Request) []string {
+	return []string{
+		"version: " + state.Version,
+		"verbosity: " + strconv.Itoa(state.Verbosity),
+	}
+}
+
+func handleStats(state *State, req Request) []string {
+	keys := slices.Sorted(maps.Keys(state.Stats))
+	reply := make([]string, 0, len(keys))
+	for _, key := range keys {
+		reply = append(reply, key+"="+state.Stats[key])
+	}
+	if req.Command == "stats" {
+		for _, key := range keys {
+			if strings.HasPrefix(key, "num.") || strings.HasSuffix(key, ".queries") {
+				state.Stats[key] = "0"
+			}
+		}
+	}
+	return reply
+}
+
+func handleServerPID(state *State, req Request) []string {
+	return []string{strconv.Itoa(state.PID)}
+}
+
+func handleVerbosity(state *State, req Request) []string {
+	if len(req.Args) != 1 {
+		return errorf("error: missing argument (verbosity level)")
+	}
+	verbosity, err := strconv.Atoi(req.Args[0])
+	if err != nil {
+		return errorf("error: bad number %s", req.Args[0])
+	}
+	state.Verbosity = verbosity
+	return replyOK
+}
+
+// addZone adds a zone from a pattern, returning the error reply if it can't
+func addZone(state *State, name string, pattern string) string {
+	if state.Zone(name) != nil {
+		return fmt.Sprintf("error zone %s already exists", name)
+	}
+	zoneState, ok := state.Patterns[pattern]
+	if !ok {
+		return fmt.Sprintf("error pattern %s does not exist", pattern)
+	}
+	state.AddZone(&Zone{Name: name, Pattern: pattern, State: zoneState})
+	return ""
+}
+
+// delZone deletes a zone, returning the error reply if it can't
+func delZone(state *State, name string) string {
+	if state.Zone(name) == nil {
+		return fmt.Sprintf("error zone %s is not present", name)
+	}
+	delete(state.Zones, zoneKey(name))
+	return ""
+}
+
+func handleAddZone(state *State, req Request) []string {
+	if len(req.Args) != 2 {
+		return errorf("error: missing argument (zone and pattern)")
+	}
+	if reply := addZone(state, req.Args[0], req.Args[1]); reply != "" {
+		return []string{reply}
+	}
+	return replyOK
+}
+
+func handleDelZone(state *State, req Request) []string {
+	if len(req.Args) != 1 {
+		return errorf("error: missing argument (zone)")
+	}
+	if reply := delZone(state, req.Args[0]); reply != "" {
+		return []string{reply}
+	}
+	return replyOK
+}
+
+func handleChangeZone(state *State, req Request) []string {
+	if len(req.Args) != 2 {
+		return errorf("error: missing argument (zone and pattern)")
+	}
+	zone := state.Zone(req.Args[0])
+	if zone == nil {
+		return errorf("error zone %s is not present", req.Args[0])
+	}
+	if _, ok := state.Patterns[req.Args[1]]; !ok {
+		return errorf("error pattern %s does not exist", req.Args[1])
+	}
+	zone.Pattern = req.Args[1]
+	return replyOK
+}
+
+func handleAddZones(state *State, req Request) []string {
+	var reply []string
+	added := 0
+	for _, line := range req.Lines {
+		fields := strings.Fields(line)
+		if len(fields) != 2 {
+			reply = append(reply, fmt.Sprintf("error for input line '%s'", line))
+		} else if err := addZone(state, fields[0], fields[1]); err != "" {
+			reply = append(reply, err)
+		} else {
+			added++
+		}
+	}
+	return append(reply, fmt.Sprintf("added: %d", added))
+}
+
+func handleDelZones(state *State, req Request) []string {
+	var reply []string
+	deleted := 0
+	for _, line := range req.Lines {
+		if err := delZone(state, strings.TrimSpace(line)); err != "" {
+			reply = append(reply, err)
+		} else {
+			deleted++
+		}
+	}
+	return append(reply, fmt.Sprintf("deleted: %d", deleted))
+}
+
+func handleZoneStatus(state *State, req Request) []string {
+	var zones []*Zone
+	if len(req.Args) > 0 {
+		zone, reply := zoneArg(state, req)
+		if zone == nil {
+			return reply
+		}
+		zones = append(zones, zone)
+	} else {
+		for _, key := range slices.Sorted(maps.Keys(state.Zones)) {
+			zones = append(zones, state.Zones[key])
+		}
+	}
+
+	var reply []string
+	for _, zone := range zones {
+		reply = append(reply, "zone:\t"+zone.Name)
+		if zone.Pattern != "" {
+			reply = append(reply, "\tpattern: "+zone.Pattern)
+		}
+		reply = append(reply, "\tstate: "+string(zone.State))
+		if zone.State != client.ZoneStatePrimary {
+			reply = append(reply,
+				"\tserved-serial: "+formatSerial(zone.ServedSerial),
+				"\tcommit-serial: "+formatSerial(zone.CommitSerial))
+		}
+	}
+	return reply
+}
+
+func formatSerial(serial *client.ZoneSerial) string {
+	if serial == nil {
+		return "none"
+	}
+	if serial.Since.IsZero() {
+		return fmt.Sprintf(`"%d"`, serial.Serial)
+	}
+	return fmt.Sprintf(`"%d since %s"`, serial.Serial, serial.Since.Format("2006-01-02T15:04:05"))
+}
+
+func formatKey(key client.TsigKey) string {
+	return fmt.Sprintf(`key: name: "%s" secret: "%s" algorithm: %s`, key.Name, key.Secret, key.Algorithm)
+}
+
+func handlePrintTsig(state *State, req Request) []string {
+	if len(req.Args) > 0 {
+		key, ok := state.Keys[req.Args[0]]
+		if !ok {
+			return errorf("error: no such key with name: %s", req.Args[0])
+		}
+		return []string{formatKey(key)}
+	}
+	var reply []string
+	for _, name := range slices.Sorted(maps.Keys(state.Keys)) {
+		reply = append(reply, formatKey(state.Keys[name]))
+	}
+	return reply
+}
+
+// validSecret reports whether NSD accepts a TSIG secret
+func validSecret(secret string) bool {
+	_, err := base64.StdEncoding.DecodeString(secret)
+	return err == nil
+}
+
+func handleAddTsig(state *State, req Request) []string {
+	if len(req.Args) < 2 {
+		return errorf("error: missing argument (key name and secret)")
+	}
+	name, secret := req.Args[0], req.Args[1]
+	algorithm := client.TsigHmacSHA256
+	if len(req.Args) > 2 {
+		algorithm = client.TsigAlgorithm(req.Args[2])
+	}
+	if _, ok := state.Keys[name]; ok {
+		return errorf("error: key %s already exists", name)
+	}
+	if !validSecret(secret) {
+		return errorf("error: the secret: %s is not in base64 format", secret)
+	}
+	if !algorithm.Valid() {
+		return errorf("error: unsupported algorithm: %s", algorithm)
+	}
+	state.Keys[name] = client.TsigKey{Name: name, Algorithm: algorithm, Secret: secret}
+	return replyOK
+}
+
+func handleUpdateTsig(state *State, req Request) []string {
+	if len(req.Args) != 2 {
+		return errorf("error: missing argument (key name and secret)")
+	}
+	key, ok := state.Keys[req.Args[0]]
+	if !ok {
+		return errorf("error: no such key with name: %s", req.Args[0])
+	}
+	if !validSecret(req.Args[1]) {
+		return errorf("error: the secret: %s is not in base64 format", req.Args[1])
+	}
+	key.Secret = req.Args[1]
+	state.Keys[key.Name] = key
+	return replyOK
+}
+
+func handleAssocTsig(state *State, req Request) []string {
+	if len(req.Args) != 2 {
+		return errorf("error: missing argument (zone and key name)")
+	}
+	zone := state.Zone(req.Args[0])
+	if zone == nil {
+		return errorf("error: zone: %s does not exist", req.Args[0])
+	}
+	if _, ok := state.Keys[req.Args[1]]; !ok {
+		return errorf("error: key: %s does not exist", req.Args[1])
+	}
+	zone.Key = req.Args[1]
+	return replyOK
+}
+
+func handleDelTsig(state *State, req Request) []string {
+	if len(req.Args) != 1 {
+		return errorf("error: missing argument (key name)")
+	}
+	name := req.Args[0]
+	if _, ok := state.Keys[name]; !ok {
+		return errorf("error: key: %s does not exist", name)
+	}
+	for _, key := range slices.Sorted(maps.Keys(state.Zones)) {
+		if zone := state.Zones[key]; zone.Key == name {
+			return errorf("error: key: %s is in use by zone %s", name, zone.Name)
+		}
+	}
+	delete(state.Keys, name)
+	return replyOK
+}
+
+func handleAddCookieSecret(state *State, req Request) []string {
+	if len(req.Args) != 1 {
+		return errorf("error: missing argument (cookie_secret)")
+	}
+	if decoded, err := hex.DecodeString(req.Args[0]); err != nil || len(decoded) != 16 {
+		return []string{
+			"invalid cookie secret: invalid argument length",
+			"please provide a 128bit hex encoded secret",
+		}
+	}
+	state.Cookies.Staging = req.Args[0]
+	return replyOK
+}
+
+func handleDropCookieSecret(state *State, req Request) []string {
+	if state.Cookies.Staging == "" {
+		return errorf("error: cannot drop cookie secret as no staging secret is available")
+	}
+	state.Cookies.Staging = ""
+	return replyOK
+}
+
+func handleActivateCookieSecret(state *State, req Request) []string {
+	if state.Cookies.Staging == "" {
+		return errorf("error: no staging cookie secret to activate")
+	}
+	state.Cookies.Active, state.Cookies.Staging = state.Cookies.Staging, state.Cookies.Active
+	return replyOK
+}
+
+func handlePrintCookieSecrets(state *State, req Request) []string {
+	source := state.Cookies.Source
+	if strings.HasPrefix(source, "/") {
+		source = `"` + source + `"`
+	}
+	reply := []string{
+		"source : " + source,
+		"active : " + state.Cookies.Active,
+	}
+	if state.Cookies.Staging != "" {
+		reply = append(reply, "staging: "+state.Cookies.Staging)
+	}
+	return reply
+}
@@ -0,0 +1,283 @@
+// Package nsdtest runs a fake NSD control server for hermetic end-to-end tests of code using the client.
+//
+// The server speaks the NSDCT1 protocol over a unix socket or mutual TLS over TCP, handling a single command per
+// connection like NSD. Commands read and change an in-memory State, and can be overridden with Handle:
+//
+//	server := nsdtest.NewUNIXServer(nil)
+//	defer server.Close()
+//	c := server.Client()
+//	statuses, err := c.ZoneStatuses()
+//
+// ChaosDialer injects faults such as resets, truncated lines and stalls into the replies of any dialer.
+package nsdtest
+
+import (
+	"bufio"
+	"crypto/tls"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"nsd/pkg/client"
+)
+
+const (
+	// header starts every command, https://github.com/NLnetLabs/nsd/blob/NSD_4_11_0_REL/remote.c (remote_handshake)
+	header = "NSDCT1 "
+	// endOfTransmission ends the zones sent after addzones and delzones
+	endOfTransmission = "\x04"
+	// connTimeout limits how long a connection is served, so an abandoned client doesn't block Close
+	connTimeout = 10 * time.Second
+)
+
+// Request is a command received by a fake server
+type Request struct {
+	Command string
+	Args    []string
+	// Lines are the lines sent after addzones and delzones, without the end of transmission
+	Lines []string
+}
+
+// HandlerFunc replies to a command with the returned lines, the state may be changed.
+// Handlers are called one at a time.
+type HandlerFunc func(state *State, req Request) []string
+
+// Server is a fake NSD control server
+type Server struct {
+	listener net.Listener
+	// tempDir holds the unix socket
+	tempDir string
+	// clientTLS is the client's TLS config for TLS servers
+	clientTLS *tls.Config
+
+	mu       sync.Mutex
+	state    *State
+	handlers map[string]HandlerFunc
+	requests []Request
+	conns    map[net.Conn]struct{}
+	closed   bool
+	wg       sync.WaitGroup
+}
+
+// NewUNIXServer starts a fake server on a unix socket in a temporary directory, a nil state is replaced by DefaultState.
+// It panics if the socket can't be created, like httptest.NewServer.
+func NewUNIXServer(state *State) *Server {
+	dir, err := os.MkdirTemp("", "nsdtest")
+	if err != nil {
+		panic(fmt.Sprintf("nsdtest: %v", err))
+	}
+	l, err := net.Listen("unix", filepath.Join(dir, "nsd.sock"))
+	if err != nil {
+		_ = os.RemoveAll(dir)
+		panic(fmt.Sprintf("nsdtest: %v", err))
+	}
+	s := newServer(l, state)
+	s.tempDir = dir
+	return s
+}
+
+// NewTLSServer starts a fake server on the TCP loopback address, requiring clients to authenticate with the
+// certificate in ClientTLSConfig. A nil state is replaced by DefaultState.
+// It panics if the certificates or the listener can't be created, like httptest.NewServer.
+func NewTLSServer(state *State) *Server {
+	certs, err := newCertificates()
+	if err != nil {
+		panic(fmt.Sprintf("nsdtest: %v", err))
+	}
+	l, err := tls.Listen("tcp", "127.0.0.1:0", certs.serverConfig())
+	if err != nil {
+		panic(fmt.Sprintf("nsdtest: %v", err))
+	}
+	s := newServer(l, state)
+	s.clientTLS = certs.clientConfig()
+	return s
+}
+
+func newServer(l net.Listener, state *State) *Server {
+	if state == nil {
+		state = DefaultState()
+	}
+	s := &Server{
+		listener: l,
+		state:    state,
+		handlers: make(map[string]HandlerFunc),
+		conns:    make(map[net.Conn]struct{}),
+	}
+	s.wg.Add(1)
+	go s.serve()
+	return s
+}
+
+// Addr is the address of the server, a *net.UnixAddr or a *net.TCPAddr
+func (s *Server) Addr() net.Addr {
+	return s.listener.Addr()
+}
+
+// ClientTLSConfig returns the TLS config for connecting to a TLS server, with the client certificate and the CA
+// of the server certificate, issued to "nsd" and the loopback addresses. It is nil for unix servers.
+func (s *Server) ClientTLSConfig() *tls.Config {
+	if s.clientTLS == nil {
+		return nil
+	}
+	return s.clientTLS.Clone()
+}
+
+// Dialer returns a dialer connecting to the server
+func (s *Server) Dialer() *client.NetDialer {
+	if s.clientTLS != nil {
+		return client.TLSDialer(s.Addr(), s.ClientTLSConfig())
+	}
+	return client.UNIXDialer(s.Addr().String())
+}
+
+// Client returns a client connecting to the server for every command
+func (s *Server) Client(opts ...client.Option) *client.Client {
+	return client.New(s.Dialer(), opts...)
+}
+
+// Handle replaces the handler of a command, e.g. to reply with an error, nil restores the built-in handler
+func (s *Server) Handle(cmd string, handler HandlerFunc) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if handler == nil {
+		delete(s.handlers, cmd)
+	} else {
+		s.handlers[cmd] = handler
+	}
+}
+
+// State returns a copy of the current state
+func (s *Server) State() *State {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.state.Clone()
+}
+
+// Update changes the state, without racing with the commands being handled
+func (s *Server) Update(f func(state *State)) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	f(s.state)
+}
+
+// Requests returns the commands received so far, in order
+func (s *Server) Requests() []Request {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return slices.Clone(s.requests)
+}
+
+// Close stops the server, closing the connections being served
+func (s *Server) Close() {
+	s.mu.Lock()
+	if s.closed {
+		s.mu.Unlock()
+		return
+	}
+	s.closed = true
+	_ = s.listener.Close()
+	for conn := range s.conns {
+		_ = conn.Close()
+	}
+	s.mu.Unlock()
+
+	s.wg.Wait()
+	if s.tempDir != "" {
+		_ = os.RemoveAll(s.tempDir)
+	}
+}
+
+func (s *Server) serve() {
+	defer s.wg.Done()
+	for {
+		conn, err := s.listener.Accept()
+		if err != nil {
+			return
+		}
+		s.mu.Lock()
+		if s.closed {
+			s.mu.Unlock()
+			_ = conn.Close()
+			return
+		}
+		s.conns[conn] = struct{}{}
+		s.wg.Add(1)
+		s.mu.Unlock()
+
+		go func() {
+			defer s.wg.Done()
+			s.serveConn(conn)
+			s.mu.Lock()
+			delete(s.conns, conn)
+			s.mu.Unlock()
+			_ = conn.Close()
+		}()
+	}
+}
+
+// serveConn handles a single command, like NSD does before closing the connection
+func (s *Server) serveConn(conn net.Conn) {
+	_ = conn.SetDeadline(time.Now().Add(connTimeout))
+	req, ok := readRequest(conn)
+	if !ok {
+		// NSD closes the connection without a reply
+		return
+	}
+
+	s.mu.Lock()
+	s.requests = append(s.requests, req)
+	handler, ok := s.handlers[req.Command]
+	if !ok {
+		handler, ok = builtinHandlers[req.Command]
+	}
+	var reply []string
+	if ok {
+		reply = handler(s.state, req)
+	} else {
+		reply = unknownCommand(req.Command)
+	}
+	s.mu.Unlock()
+
+	writeReply(conn, reply)
+}
+
+// readRequest reads the handshake and the command, and the zones following addzones and delzones
+func readRequest(r io.Reader) (Request, bool) {
+	scanner := bufio.NewScanner(r)
+	if !scanner.Scan() {
+		return Request{}, false
+	}
+	line, ok := strings.CutPrefix(scanner.Text(), header)
+	if !ok {
+		return Request{}, false
+	}
+	fields := strings.Fields(line)
+	if len(fields) == 0 {
+		return Request{}, true
+	}
+	req := Request{Command: fields[0], Args: fields[1:]}
+	if req.Command == "addzones" || req.Command == "delzones" {
+		for scanner.Scan() && scanner.Text() != endOfTransmission {
+			req.Lines = append(req.Lines, scanner.Text())
+		}
+	}
+	return req, true
+}
+
+func unknownCommand(cmd string) []string {
+	return []string{fmt.Sprintf("error unknown command '%s'", cmd)}
+}
+
+func writeReply(w io.Writer, reply []string) {
+	bw := bufio.NewWriter(w)
+	for _, line := range reply {
+		_, _ = bw.WriteString(line + "\n")
+	}
+	_ = bw.Flush()
+}
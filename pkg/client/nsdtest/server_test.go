@@ -0,0 +1,156 @@
+package nsdtest
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+
+	"nsd/pkg/client"
+)
+
+func TestServer(t *testing.T) {
+	for name, newServer := range map[string]func(*State) *Server{
+		"unix": NewUNIXServer,
+		"tls":  NewTLSServer,
+	} {
+		t.Run(name, func(t *testing.T) {
+			server := newServer(nil)
+			defer server.Close()
+			c := server.Client()
+			defer func() { _ = c.Close() }()
+
+			status, err := c.Status()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if status.Version.String() != "4.11.0" || status.Verbosity != 1 {
+				t.Errorf("Status() = %+v", status)
+			}
+			if pid, err := c.ServerPID(); err != nil || pid != 1234 {
+				t.Errorf("ServerPID() = %v, %v", pid, err)
+			}
+
+			if err := c.AddZone("example.net", "replica"); err != nil {
+				t.Fatal(err)
+			}
+			if err := c.AddZone("example.net", "replica"); !errors.Is(err, client.ErrZoneExists) {
+				t.Errorf("AddZone() error = %v, want %v", err, client.ErrZoneExists)
+			}
+			if err := c.AddZone("example.info", "missing"); !errors.Is(err, client.ErrPatternNotFound) {
+				t.Errorf("AddZone() error = %v, want %v", err, client.ErrPatternNotFound)
+			}
+			statuses, err := c.ZoneStatuses(client.ZonePatternFilter("replica"))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(statuses) != 1 || statuses[0].Zone != "example.net" || statuses[0].State != client.ZoneStateRefreshing {
+				t.Errorf("ZoneStatuses() = %+v", statuses)
+			}
+			zoneStatus, err := c.ZoneStatus("example.org")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if zoneStatus.ServedSerial == nil || zoneStatus.ServedSerial.Serial != 2024010100 {
+				t.Errorf("ZoneStatus() = %+v", zoneStatus)
+			}
+			if _, err := c.ZoneStatus("example.info"); !errors.Is(err, client.ErrZoneNotFound) {
+				t.Errorf("ZoneStatus() error = %v, want %v", err, client.ErrZoneNotFound)
+			}
+
+			result, err := c.Reload([]string{"example.com", "example.info"})
+			if !errors.Is(err, client.ErrZoneNotFound) || len(result.Failed) != 1 {
+				t.Errorf("Reload() = %+v, %v", result, err)
+			}
+			// NSD takes the whole argument as a single zone name
+			if reply, err := c.Do(context.Background(), "reload", "example.com", "example.org"); err == nil {
+				t.Errorf("Do() = %+v, want error", reply)
+			}
+			bulk, err := c.AddZones([]client.ZonePattern{{Zone: "a.example", Pattern: "replica"}, {Zone: "example.net", Pattern: "replica"}})
+			if !errors.Is(err, client.ErrZoneExists) || len(bulk.Succeeded) != 1 || bulk.Failed["example.net"] == "" {
+				t.Errorf("AddZones() = %+v, %v", bulk, err)
+			}
+			bulk, err = c.AddZones([]client.ZonePattern{{Zone: "a.example", Pattern: "nope"}, {Zone: "b.example", Pattern: "replica"}})
+			if err == nil || !reflect.DeepEqual(bulk.Succeeded, []string{"b.example"}) || bulk.Failed["a.example"] == "" {
+				t.Errorf("AddZones() = %+v, %v", bulk, err)
+			}
+			if err := c.DelZone("b.example"); err != nil {
+				t.Fatal(err)
+			}
+			if bulk, err := c.DelZones([]string{"a.example", "example.net"}); err != nil || len(bulk.Succeeded) != 2 {
+				t.Errorf("DelZones() = %+v, %v", bulk, err)
+			}
+
+			if err := c.AddTSig("key3", "K2tf3TRjvQkVCmJF3/Z9vA==", nil); err != nil {
+				t.Fatal(err)
+			}
+			if err := c.AssocTSig("example.com", "key3"); err != nil {
+				t.Fatal(err)
+			}
+			var inUse *client.KeyInUseError
+			if err := c.DelTSig("key3"); !errors.As(err, &inUse) || inUse.Zone != "example.com" {
+				t.Errorf("DelTSig() error = %v, want in use by example.com", err)
+			}
+			if _, err := c.GetTSig("key4"); !errors.Is(err, client.ErrKeyNotFound) {
+				t.Errorf("GetTSig() error = %v, want %v", err, client.ErrKeyNotFound)
+			}
+			keys, err := c.GetTSigs()
+			if err != nil || len(keys) != 3 {
+				t.Errorf("GetTSigs() = %v, %v", keys, err)
+			}
+
+			if err := c.AddCookieSecret("cd0636b6a5f8b9b1004b2450155ffca1"); err != nil {
+				t.Fatal(err)
+			}
+			if err := c.AddCookieSecret("cd06"); !errors.Is(err, client.ErrInvalidCookieSecret) {
+				t.Errorf("AddCookieSecret() error = %v, want %v", err, client.ErrInvalidCookieSecret)
+			}
+			if err := c.ActivateCookieSecret(); err != nil {
+				t.Fatal(err)
+			}
+			secrets, err := c.GetCookieSecrets()
+			if err != nil || secrets.Active != "cd0636b6a5f8b9b1004b2450155ffca1" || secrets.Staging == nil {
+				t.Errorf("GetCookieSecrets() = %+v, %v", secrets, err)
+			}
+
+			if _, err := c.Stats(); err != nil {
+				t.Fatal(err)
+			}
+			if err := c.Stop(); err != nil {
+				t.Fatal(err)
+			}
+			if state := server.State(); !state.Stopped || state.Zone("example.com").Reloads != 1 {
+				t.Errorf("State() = %+v", state)
+			}
+		})
+	}
+}
+
+func TestServer_Handle(t *testing.T) {
+	server := NewUNIXServer(nil)
+	defer server.Close()
+	c := server.Client()
+
+	server.Handle("repattern", func(state *State, req Request) []string {
+		return []string{"reconfig start, read /etc/nsd/nsd.conf", "/etc/nsd/nsd.conf:3: error: syntax error"}
+	})
+	if _, err := c.Repattern(); err == nil {
+		t.Error("Repattern() succeeded with a config error")
+	}
+	server.Handle("repattern", nil)
+	if _, err := c.Repattern(); err != nil {
+		t.Errorf("Repattern() error = %v", err)
+	}
+
+	server.Update(func(state *State) {
+		state.Version = "4.3.0"
+	})
+	if status, err := c.Status(); err != nil || status.SupportsCommand("add_cookie_secret") {
+		t.Errorf("Status() = %+v, %v", status, err)
+	}
+
+	requests := server.Requests()
+	if len(requests) != 3 || requests[2].Command != "status" {
+		t.Errorf("Requests() = %+v", requests)
+	}
+}
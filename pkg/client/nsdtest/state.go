@@ -0,0 +1,138 @@
+package nsdtest
+
+import (
+	"maps"
+	"strings"
+	"time"
+
+	"nsd/pkg/client"
+)
+
+// State is the configuration and runtime state of a fake server, read and changed by the commands it handles
+type State struct {
+	Version   string
+	Verbosity int
+	PID       int
+	// ConfigFile is reported by reconfig and repattern
+	ConfigFile string
+	// ConfigErrors are replied to reconfig and repattern instead of ok, like NSD reports a config it can't parse
+	ConfigErrors []string
+
+	// Zones are the configured zones, keyed by name in lower case without the trailing dot
+	Zones map[string]*Zone
+	// Patterns maps the configured patterns to the state of zones added with them
+	Patterns map[string]client.ZoneState
+	// Keys are the configured TSIG keys, keyed by name
+	Keys    map[string]client.TsigKey
+	Cookies CookieSecrets
+	// Stats are printed by stats and stats_noreset, stats resets the counters to zero
+	Stats map[string]string
+
+	// Stopped is set by the stop command, the fake server keeps running
+	Stopped bool
+	// LogReopens counts the log_reopen commands
+	LogReopens int
+}
+
+// Zone is a zone configured in a fake server
+type Zone struct {
+	Name    string
+	Pattern string
+	State   client.ZoneState
+	// ServedSerial and CommitSerial are reported for secondary zones, nil is reported as none
+	ServedSerial *client.ZoneSerial
+	CommitSerial *client.ZoneSerial
+	// Key is the TSIG key associated with assoc_tsig
+	Key string
+
+	// Reloads, Writes, Notifies and Transfers count the commands applied to the zone,
+	// transfer and force_transfer are both counted as transfers
+	Reloads   int
+	Writes    int
+	Notifies  int
+	Transfers int
+}
+
+// CookieSecrets are the DNS cookie secrets of a fake server, an empty Staging is not printed
+type CookieSecrets struct {
+	Source  string
+	Active  string
+	Staging string
+}
+
+// DefaultState returns a state matching the configuration of the Docker test setup in test/config/nsd.conf
+func DefaultState() *State {
+	since := time.Date(2024, 1, 1, 0, 0, 0, 0, time.Local)
+	state := &State{
+		Version:    "4.11.0",
+		Verbosity:  1,
+		PID:        1234,
+		ConfigFile: "/etc/nsd/nsd.conf",
+		Zones:      make(map[string]*Zone),
+		Patterns:   map[string]client.ZoneState{"replica": client.ZoneStateRefreshing},
+		Keys: map[string]client.TsigKey{
+			"test":  {Name: "test", Algorithm: client.TsigHmacSHA256, Secret: "5c9cfa3645f0e0036f8f886c502b1089"},
+			"test2": {Name: "test2", Algorithm: client.TsigHmacSHA512, Secret: "11c9b50555fd6bb75979d270993734ff"},
+		},
+		Cookies: CookieSecrets{
+			Source: "random generated",
+			Active: "8234dff32ace962428c8da3d22da0d49",
+		},
+		Stats: map[string]string{
+			"num.queries":     "0",
+			"server0.queries": "0",
+			"time.boot":       "0.000000",
+			"time.elapsed":    "0.000000",
+			"zone.primary":    "1",
+			"zone.secondary":  "1",
+		},
+	}
+	state.AddZone(&Zone{Name: "example.com", State: client.ZoneStatePrimary})
+	state.AddZone(&Zone{
+		Name:         "example.org",
+		State:        client.ZoneStateOK,
+		ServedSerial: &client.ZoneSerial{Serial: 2024010100, Since: since},
+		CommitSerial: &client.ZoneSerial{Serial: 2024010100, Since: since},
+	})
+	return state
+}
+
+// AddZone adds or replaces a zone
+func (s *State) AddZone(zone *Zone) {
+	if s.Zones == nil {
+		s.Zones = make(map[string]*Zone)
+	}
+	s.Zones[zoneKey(zone.Name)] = zone
+}
+
+// Zone returns the zone with the given name, ignoring case and the trailing dot, or nil
+func (s *State) Zone(name string) *Zone {
+	return s.Zones[zoneKey(name)]
+}
+
+// Clone returns a deep copy of the state
+func (s *State) Clone() *State {
+	clone := *s
+	clone.ConfigErrors = append([]string(nil), s.ConfigErrors...)
+	clone.Zones = make(map[string]*Zone, len(s.Zones))
+	for name, zone := range s.Zones {
+		z := *zone
+		if zone.ServedSerial != nil {
+			serial := *zone.ServedSerial
+			z.ServedSerial = &serial
+		}
+		if zone.CommitSerial != nil {
+			serial := *zone.CommitSerial
+			z.CommitSerial = &serial
+		}
+		clone.Zones[name] = &z
+	}
+	clone.Patterns = maps.Clone(s.Patterns)
+	clone.Keys = maps.Clone(s.Keys)
+	clone.Stats = maps.Clone(s.Stats)
+	return &clone
+}
+
+func zoneKey(name string) string {
+	return strings.ToLower(strings.TrimSuffix(name, "."))
+}
@@ -0,0 +1,89 @@
+package client
+
+import (
+	"context"
+	"io"
+	"net"
+	"strings"
+	"time"
+)
+
+// CommandEvent describes a completed command, see CommandObserver
+type CommandEvent struct {
+	// Command is the command name, e.g. "zonestatus", empty if connecting to the server failed
+	Command string
+	// Line is the command line with secrets redacted
+	Line string
+	// Zone is the zone the command applies to, empty for commands without a zone or with several zones
+	Zone string
+	// Network is the network of the connection, e.g. "unix" or "tcp", empty if unknown
+	Network string
+	// Address is the address of the server, e.g. the path of the unix socket, empty if unknown
+	Address string
+	// Start is the time the command started, including connecting to the server
+	Start    time.Time
+	Duration time.Duration
+	// Result classifies Err as ok, server_error, protocol_error, canceled, timeout, connection_error or error
+	Result string
+	Err    error
+	// ErrMessage is the message of Err with secrets redacted, as written to the logs
+	ErrMessage string
+}
+
+// CommandObserver is notified of every completed command, e.g. to record traces and metrics.
+// It is called synchronously once the connection is released, and must be safe for concurrent use.
+type CommandObserver interface {
+	ObserveCommand(ctx context.Context, event CommandEvent)
+}
+
+// SetObserver sets the observer notified of commands, nil disables it.
+// It must not be called concurrently with commands.
+func (c *Client) SetObserver(observer CommandObserver) {
+	c.observer = observer
+}
+
+// WithObserver sets the observer notified of commands, see SetObserver
+func WithObserver(observer CommandObserver) Option {
+	return func(o *options) {
+		o.observer = observer
+	}
+}
+
+// zoneCmds are the commands taking a zone as first argument, list commands only apply to a single zone with one argument
+var zoneCmds = map[string]bool{
+	cmdAddZone:       false,
+	cmdAssociateTsig: false,
+	cmdChangeZone:    false,
+	cmdDelZone:       false,
+	cmdZoneStatus:    false,
+	cmdForceTransfer: true,
+	cmdNotify:        true,
+	cmdReload:        true,
+	cmdTransfer:      true,
+	cmdWrite:         true,
+}
+
+func newCommandEvent(line string, conn io.ReadWriteCloser, start time.Time, duration time.Duration, err error) CommandEvent {
+	event := CommandEvent{
+		Line:     line,
+		Start:    start,
+		Duration: duration,
+		Result:   resultClass(err),
+		Err:      err,
+	}
+	if err != nil {
+		event.ErrMessage = redactReplyLine(err.Error())
+	}
+	fields := strings.Fields(line)
+	if len(fields) > 0 {
+		event.Command = fields[0]
+	}
+	if list, ok := zoneCmds[event.Command]; ok && len(fields) > 1 && (!list || len(fields) == 2) {
+		event.Zone = fields[1]
+	}
+	if netConn, ok := conn.(net.Conn); ok && netConn.RemoteAddr() != nil {
+		event.Network = netConn.RemoteAddr().Network()
+		event.Address = netConn.RemoteAddr().String()
+	}
+	return event
+}
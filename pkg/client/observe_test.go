@@ -0,0 +1,60 @@
+package client
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+)
+
+func Test_newCommandEvent(t *testing.T) {
+	tests := []struct {
+		line        string
+		wantCommand string
+		wantZone    string
+	}{
+		{line: "zonestatus example.com", wantCommand: cmdZoneStatus, wantZone: "example.com"},
+		{line: "zonestatus", wantCommand: cmdZoneStatus},
+		{line: "reload example.com", wantCommand: cmdReload, wantZone: "example.com"},
+		{line: "reload example.com example.net", wantCommand: cmdReload},
+		{line: "addzone example.com primary", wantCommand: cmdAddZone, wantZone: "example.com"},
+		{line: "add_tsig key1 [REDACTED]", wantCommand: cmdAddTsig},
+		{line: "", wantCommand: ""},
+	}
+	for _, tt := range tests {
+		event := newCommandEvent(tt.line, nil, time.Now(), time.Second, nil)
+		if event.Command != tt.wantCommand || event.Zone != tt.wantZone || event.Result != "ok" {
+			t.Errorf("newCommandEvent(%q) = %+v, want command %q zone %q", tt.line, event, tt.wantCommand, tt.wantZone)
+		}
+	}
+}
+
+type recordingObserver struct {
+	mu     sync.Mutex
+	events []CommandEvent
+}
+
+func (r *recordingObserver) ObserveCommand(ctx context.Context, event CommandEvent) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.events = append(r.events, event)
+}
+
+func TestClient_SetObserver(t *testing.T) {
+	observer := &recordingObserver{}
+	c := New(pipeDialer(map[string]string{cmdServerPID: "1234\n"}), WithObserver(observer))
+	if _, err := c.ServerPID(); err != nil {
+		t.Fatal(err)
+	}
+	c.SetObserver(nil)
+	if _, err := c.ServerPID(); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(observer.events) != 1 {
+		t.Fatalf("observed %d commands, want 1", len(observer.events))
+	}
+	if event := observer.events[0]; event.Command != cmdServerPID || event.Network != "pipe" || event.Duration <= 0 {
+		t.Errorf("observed %+v", event)
+	}
+}
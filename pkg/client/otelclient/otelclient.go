@@ -0,0 +1,139 @@
+// Package otelclient instruments the NSD control client with OpenTelemetry traces and metrics.
+//
+// Instrumentation is opt-in, clients without an observer don't record anything:
+//
+//	observer, err := otelclient.NewObserver()
+//	c := client.New(dialer, client.WithObserver(observer))
+package otelclient
+
+import (
+	"context"
+	"fmt"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/metric"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
+
+	"nsd/pkg/client"
+)
+
+// ScopeName is the instrumentation scope of the tracer and meter
+const ScopeName = "nsd/pkg/client/otelclient"
+
+// Attribute keys set on spans and metrics
+const (
+	CommandKey = attribute.Key("nsd.control.command")
+	ZoneKey    = attribute.Key("nsd.zone")
+	ResultKey  = attribute.Key("nsd.control.result")
+	// TransportKey and AddressKey follow the OpenTelemetry semantic conventions
+	TransportKey = attribute.Key("network.transport")
+	AddressKey   = attribute.Key("server.address")
+)
+
+// Observer records a span and metrics for every command, it implements client.CommandObserver
+type Observer struct {
+	tracer   trace.Tracer
+	duration metric.Float64Histogram
+	errors   metric.Int64Counter
+}
+
+// Option configures an Observer
+type Option func(*config)
+
+type config struct {
+	tracerProvider trace.TracerProvider
+	meterProvider  metric.MeterProvider
+}
+
+// WithTracerProvider sets the tracer provider, the global provider is used by default
+func WithTracerProvider(provider trace.TracerProvider) Option {
+	return func(c *config) {
+		c.tracerProvider = provider
+	}
+}
+
+// WithMeterProvider sets the meter provider, the global provider is used by default
+func WithMeterProvider(provider metric.MeterProvider) Option {
+	return func(c *config) {
+		c.meterProvider = provider
+	}
+}
+
+// NewObserver creates an observer recording spans and metrics with the given providers
+func NewObserver(opts ...Option) (*Observer, error) {
+	cfg := config{
+		tracerProvider: otel.GetTracerProvider(),
+		meterProvider:  otel.GetMeterProvider(),
+	}
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+
+	meter := cfg.meterProvider.Meter(ScopeName)
+	duration, err := meter.Float64Histogram("nsd.control.command.duration",
+		metric.WithDescription("Duration of NSD control commands, including connecting to the server"),
+		metric.WithUnit("s"))
+	if err != nil {
+		return nil, err
+	}
+	errors, err := meter.Int64Counter("nsd.control.command.errors",
+		metric.WithDescription("Number of failed NSD control commands"),
+		metric.WithUnit("{command}"))
+	if err != nil {
+		return nil, err
+	}
+
+	return &Observer{
+		tracer:   cfg.tracerProvider.Tracer(ScopeName),
+		duration: duration,
+		errors:   errors,
+	}, nil
+}
+
+// ObserveCommand records the span and metrics of a completed command
+func (o *Observer) ObserveCommand(ctx context.Context, event client.CommandEvent) {
+	command := event.Command
+	if command == "" {
+		command = "connect"
+	}
+	// Metrics leave out the zone and address, which would make too many series
+	metricAttrs := []attribute.KeyValue{
+		CommandKey.String(command),
+		ResultKey.String(event.Result),
+	}
+	if event.Network != "" {
+		metricAttrs = append(metricAttrs, TransportKey.String(event.Network))
+	}
+
+	spanAttrs := append([]attribute.KeyValue(nil), metricAttrs...)
+	if event.Zone != "" {
+		spanAttrs = append(spanAttrs, ZoneKey.String(event.Zone))
+	}
+	if event.Address != "" {
+		spanAttrs = append(spanAttrs, AddressKey.String(event.Address))
+	}
+	_, span := o.tracer.Start(ctx, "nsd.control "+command,
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithTimestamp(event.Start),
+		trace.WithAttributes(spanAttrs...))
+	if event.Err != nil {
+		// Error messages can echo secrets, unlike span.RecordError the redacted message is recorded
+		span.AddEvent(semconv.ExceptionEventName, trace.WithAttributes(
+			semconv.ExceptionType(fmt.Sprintf("%T", event.Err)),
+			semconv.ExceptionMessage(event.ErrMessage),
+		))
+		span.SetStatus(codes.Error, event.Result)
+	}
+	span.End(trace.WithTimestamp(event.Start.Add(event.Duration)))
+
+	set := metric.WithAttributes(metricAttrs...)
+	o.duration.Record(ctx, event.Duration.Seconds(), set)
+	if event.Err != nil {
+		o.errors.Add(ctx, 1, set)
+	}
+}
+
+var _ client.CommandObserver = (*Observer)(nil)
@@ -0,0 +1,171 @@
+package otelclient
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"io"
+	"net"
+	"strings"
+	"testing"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
+	"go.opentelemetry.io/otel/sdk/metric/metricdata"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+
+	"nsd/pkg/client"
+)
+
+// pipeDialer connects to an in-memory server replying to a single command per connection
+func pipeDialer(replies map[string]string) client.DialerFunc {
+	return func(ctx context.Context) (io.ReadWriteCloser, error) {
+		clientConn, serverConn := net.Pipe()
+		go func() {
+			defer func() { _ = serverConn.Close() }()
+			scanner := bufio.NewScanner(serverConn)
+			if !scanner.Scan() {
+				return
+			}
+			_, cmd, _ := strings.Cut(scanner.Text(), " ")
+			_, _ = serverConn.Write([]byte(replies[cmd]))
+		}()
+		return clientConn, nil
+	}
+}
+
+func TestObserver(t *testing.T) {
+	spans := tracetest.NewSpanRecorder()
+	reader := sdkmetric.NewManualReader()
+	observer, err := NewObserver(
+		WithTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(spans))),
+		WithMeterProvider(sdkmetric.NewMeterProvider(sdkmetric.WithReader(reader))),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	c := client.New(pipeDialer(map[string]string{
+		"zonestatus example.com": "zone: example.com\n\tstate: ok\n",
+		"zonestatus example.net": "error zone example.net not configured\n",
+	}), client.WithObserver(observer))
+	if _, err := c.ZoneStatus("example.com"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := c.ZoneStatus("example.net"); !errors.Is(err, client.ErrZoneNotFound) {
+		t.Fatalf("ZoneStatus() error = %v", err)
+	}
+	failing := client.New(client.DialerFunc(func(ctx context.Context) (io.ReadWriteCloser, error) {
+		return nil, io.EOF
+	}), client.WithObserver(observer))
+	if _, err := failing.Status(); err == nil {
+		t.Fatal("Status() succeeded without a connection")
+	}
+
+	ended := spans.Ended()
+	if len(ended) != 3 {
+		t.Fatalf("recorded %d spans, want 3", len(ended))
+	}
+	for i, want := range []struct {
+		name   string
+		attrs  []attribute.KeyValue
+		status codes.Code
+	}{
+		{
+			name:  "nsd.control zonestatus",
+			attrs: []attribute.KeyValue{CommandKey.String("zonestatus"), ResultKey.String("ok"), TransportKey.String("pipe"), ZoneKey.String("example.com")},
+		},
+		{
+			name:   "nsd.control zonestatus",
+			attrs:  []attribute.KeyValue{ResultKey.String("server_error"), ZoneKey.String("example.net")},
+			status: codes.Error,
+		},
+		{
+			name:   "nsd.control connect",
+			attrs:  []attribute.KeyValue{ResultKey.String("connection_error")},
+			status: codes.Error,
+		},
+	} {
+		span := ended[i]
+		if span.Name() != want.name {
+			t.Errorf("span %d name = %q, want %q", i, span.Name(), want.name)
+		}
+		if span.Status().Code != want.status {
+			t.Errorf("span %d status = %v, want %v", i, span.Status().Code, want.status)
+		}
+		for _, attr := range want.attrs {
+			if !hasAttribute(span.Attributes(), attr) {
+				t.Errorf("span %d attributes %v don't contain %v", i, span.Attributes(), attr)
+			}
+		}
+		if !span.EndTime().After(span.StartTime()) {
+			t.Errorf("span %d ends at %v before it starts at %v", i, span.EndTime(), span.StartTime())
+		}
+	}
+
+	var metrics metricdata.ResourceMetrics
+	if err := reader.Collect(context.Background(), &metrics); err != nil {
+		t.Fatal(err)
+	}
+	counts := map[string]uint64{}
+	for _, scope := range metrics.ScopeMetrics {
+		for _, m := range scope.Metrics {
+			switch data := m.Data.(type) {
+			case metricdata.Histogram[float64]:
+				for _, point := range data.DataPoints {
+					counts[m.Name] += point.Count
+				}
+			case metricdata.Sum[int64]:
+				for _, point := range data.DataPoints {
+					counts[m.Name] += uint64(point.Value)
+				}
+			}
+		}
+	}
+	if counts["nsd.control.command.duration"] != 3 || counts["nsd.control.command.errors"] != 2 {
+		t.Errorf("metrics = %v, want 3 durations and 2 errors", counts)
+	}
+}
+
+func hasAttribute(attrs []attribute.KeyValue, want attribute.KeyValue) bool {
+	for _, attr := range attrs {
+		if attr == want {
+			return true
+		}
+	}
+	return false
+}
+
+func TestObserver_redactsErrors(t *testing.T) {
+	spans := tracetest.NewSpanRecorder()
+	observer, err := NewObserver(WithTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(spans))))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// NSD echoes a secret it cannot decode
+	const secret = "K2tf3TRjvQkVCmJF3/Z9vA="
+	c := client.New(pipeDialer(map[string]string{
+		"add_tsig key1 " + secret: "error: the secret: " + secret + " is not in base64 format\n",
+	}), client.WithObserver(observer))
+	if _, err := c.Do(context.Background(), "add_tsig", "key1", secret); err == nil {
+		t.Fatal("Do() succeeded")
+	}
+
+	ended := spans.Ended()
+	if len(ended) != 1 || len(ended[0].Events()) != 1 {
+		t.Fatalf("recorded spans %v, want 1 with an exception event", ended)
+	}
+	for _, attr := range ended[0].Events()[0].Attributes {
+		if strings.Contains(attr.Value.Emit(), secret) {
+			t.Errorf("exception attribute %v contains the secret", attr)
+		}
+	}
+	for _, attr := range ended[0].Attributes() {
+		if strings.Contains(attr.Value.Emit(), secret) {
+			t.Errorf("span attribute %v contains the secret", attr)
+		}
+	}
+}
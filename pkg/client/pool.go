@@ -0,0 +1,332 @@
+package client
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"net"
+	"sync"
+	"time"
+)
+
+const (
+	// DefaultPoolMaxConns is the default limit of open connections.
+	// NSD accepts at most 10 concurrent control connections, https://github.com/NLnetLabs/nsd/blob/NSD_4_11_0_REL/remote.c (daemon_remote_create)
+	DefaultPoolMaxConns = 4
+	// DefaultPoolMaxIdle is the default number of handshaked connections kept ready for the next command
+	DefaultPoolMaxIdle = 2
+	// DefaultPoolIdleTimeout is the default time a ready connection is kept before it is discarded.
+	// NSD closes control connections that have been idle for 120 seconds.
+	DefaultPoolIdleTimeout = 60 * time.Second
+)
+
+// ErrClientClosed is returned by commands on a pooled client that has been closed
+var ErrClientClosed = errors.New("client closed")
+
+// PoolConfig limits the connections of a pooled client, zero values are replaced with the defaults
+type PoolConfig struct {
+	// MaxConns is the maximum number of open connections, including ready connections.
+	// Commands wait for a connection once the limit is reached.
+	MaxConns int
+	// MaxIdle is the number of handshaked connections kept ready for the next command, negative disables it
+	MaxIdle int
+	// IdleTimeout discards ready connections before NSD times them out
+	IdleTimeout time.Duration
+}
+
+// PoolStats are the metrics of a pooled client
+type PoolStats struct {
+	// Active is the number of commands in progress
+	Active int
+	// Idle is the number of handshaked connections ready for the next command
+	Idle int
+	// Dials is the number of connections opened
+	Dials uint64
+	// DialErrors is the number of connections that failed to open or handshake
+	DialErrors uint64
+	// Hits is the number of commands run on a ready connection
+	Hits uint64
+	// Misses is the number of commands that opened a new connection
+	Misses uint64
+	// Waits is the number of commands that waited because MaxConns was reached
+	Waits uint64
+	// WaitDuration is the total time commands waited because MaxConns was reached
+	WaitDuration time.Duration
+	// Expired is the number of ready connections discarded because of IdleTimeout
+	Expired uint64
+}
+
+// NewPooledUNIXSocketClient creates a client running commands concurrently on connections to the unix socket at path
+func NewPooledUNIXSocketClient(path string, config PoolConfig) *Client {
+	return newPooledClient(unixDialFunc(path), config)
+}
+
+// NewPooledTLSClient creates a client running commands concurrently on connections to addr
+func NewPooledTLSClient(addr net.Addr, tlsConfig *tls.Config, config PoolConfig) *Client {
+	return newPooledClient(tlsDialFunc(addr, tlsConfig), config)
+}
+
+func newPooledClient(dial dialFunc, config PoolConfig) *Client {
+	if config.MaxConns <= 0 {
+		config.MaxConns = DefaultPoolMaxConns
+	}
+	if config.MaxIdle == 0 {
+		config.MaxIdle = DefaultPoolMaxIdle
+	}
+	config.MaxIdle = min(max(config.MaxIdle, 0), config.MaxConns)
+	if config.IdleTimeout <= 0 {
+		config.IdleTimeout = DefaultPoolIdleTimeout
+	}
+
+	return &Client{
+		pool: &connPool{
+			dial:   dial,
+			config: config,
+			slots:  make(chan struct{}, config.MaxConns),
+			ready:  make(chan struct{}),
+		},
+		timeout: DefaultTimeout,
+	}
+}
+
+// PoolStats returns the metrics of a pooled client, or zero values for other clients
+func (c *Client) PoolStats() PoolStats {
+	if c.pool == nil {
+		return PoolStats{}
+	}
+	return c.pool.snapshot()
+}
+
+// connPool hands out handshaked connections, each used for a single command.
+// Ready connections are opened after a command completes, so the next command doesn't wait for the handshake.
+type connPool struct {
+	dial   dialFunc
+	config PoolConfig
+	// slots holds a token for every open connection
+	slots chan struct{}
+
+	mu   sync.Mutex
+	idle []idleConn
+	// dialing is the number of ready connections being opened
+	dialing int
+	// waiting is the number of commands waiting for a slot
+	waiting int
+	// ready is closed and replaced when a ready connection is added, waking waiting commands
+	ready  chan struct{}
+	closed bool
+	stats  PoolStats
+}
+
+type idleConn struct {
+	client *Client
+	since  time.Time
+}
+
+// get returns a handshaked client for a single command, the client's done function returns the connection slot.
+// If ctx has no deadline, waiting for a slot is bounded by timeout.
+func (p *connPool) get(ctx context.Context, timeout time.Duration) (*Client, error) {
+	p.mu.Lock()
+	if p.closed {
+		p.mu.Unlock()
+		return nil, ErrClientClosed
+	}
+	p.expireLocked()
+	if client := p.takeLocked(); client != nil {
+		p.mu.Unlock()
+		return client, nil
+	}
+	p.mu.Unlock()
+
+	select {
+	case p.slots <- struct{}{}:
+	default:
+		client, err := p.wait(ctx, timeout)
+		if err != nil || client != nil {
+			return client, err
+		}
+	}
+
+	p.mu.Lock()
+	p.stats.Misses++
+	p.mu.Unlock()
+	client, err := p.open(ctx)
+	if err != nil {
+		<-p.slots
+		return nil, err
+	}
+	p.mu.Lock()
+	p.stats.Active++
+	p.mu.Unlock()
+	return client, nil
+}
+
+// wait blocks until a slot is free or a ready connection is added.
+// It returns the ready connection, or nil if the caller now holds a slot.
+func (p *connPool) wait(ctx context.Context, timeout time.Duration) (*Client, error) {
+	if _, ok := ctx.Deadline(); !ok && timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+
+	start := time.Now()
+	p.mu.Lock()
+	p.waiting++
+	defer func() {
+		p.waiting--
+		p.stats.Waits++
+		p.stats.WaitDuration += time.Since(start)
+		p.mu.Unlock()
+	}()
+	for {
+		if p.closed {
+			return nil, ErrClientClosed
+		}
+		if client := p.takeLocked(); client != nil {
+			return client, nil
+		}
+		ready := p.ready
+		p.mu.Unlock()
+
+		var err error
+		select {
+		case p.slots <- struct{}{}:
+			p.mu.Lock()
+			return nil, nil
+		case <-ready:
+		case <-ctx.Done():
+			err = ctx.Err()
+		}
+		p.mu.Lock()
+		if err != nil {
+			return nil, err
+		}
+	}
+}
+
+// takeLocked returns a ready connection if there is one, p.mu must be held
+func (p *connPool) takeLocked() *Client {
+	n := len(p.idle)
+	if n == 0 {
+		return nil
+	}
+	client := p.idle[n-1].client
+	p.idle = p.idle[:n-1]
+	p.stats.Hits++
+	p.stats.Active++
+	return client
+}
+
+// open dials and handshakes a connection, the caller must hold a slot
+func (p *connPool) open(ctx context.Context) (*Client, error) {
+	p.mu.Lock()
+	p.stats.Dials++
+	p.mu.Unlock()
+
+	client, err := func() (*Client, error) {
+		conn, err := p.dial(ctx)
+		if err != nil {
+			return nil, err
+		}
+		return newClient(ctx, conn)
+	}()
+	if err != nil {
+		p.mu.Lock()
+		p.stats.DialErrors++
+		p.mu.Unlock()
+		return nil, err
+	}
+	client.done = p.done
+	return client, nil
+}
+
+// done returns the slot of a connection used by a command, and replaces it with a ready connection
+func (p *connPool) done() {
+	<-p.slots
+	p.mu.Lock()
+	p.stats.Active--
+	p.mu.Unlock()
+	p.refill()
+}
+
+// refill opens ready connections in the background until MaxIdle is reached,
+// free slots are left to waiting commands
+func (p *connPool) refill() {
+	for {
+		p.mu.Lock()
+		if p.closed || p.waiting > 0 || len(p.idle)+p.dialing >= p.config.MaxIdle {
+			p.mu.Unlock()
+			return
+		}
+		select {
+		case p.slots <- struct{}{}:
+		default:
+			p.mu.Unlock()
+			return
+		}
+		p.dialing++
+		p.mu.Unlock()
+
+		go func() {
+			ctx, cancel := context.WithTimeout(context.Background(), DefaultDialTimeout)
+			defer cancel()
+			client, err := p.open(ctx)
+
+			p.mu.Lock()
+			defer p.mu.Unlock()
+			p.dialing--
+			if err != nil {
+				<-p.slots
+				return
+			} else if p.closed {
+				_ = client.socket.Close()
+				<-p.slots
+				return
+			}
+			p.idle = append(p.idle, idleConn{client: client, since: time.Now()})
+			close(p.ready)
+			p.ready = make(chan struct{})
+		}()
+	}
+}
+
+// expireLocked discards ready connections older than IdleTimeout, p.mu must be held
+func (p *connPool) expireLocked() {
+	kept := p.idle[:0]
+	for _, conn := range p.idle {
+		if time.Since(conn.since) < p.config.IdleTimeout {
+			kept = append(kept, conn)
+			continue
+		}
+		_ = conn.client.socket.Close()
+		<-p.slots
+		p.stats.Expired++
+	}
+	clear(p.idle[len(kept):])
+	p.idle = kept
+}
+
+func (p *connPool) snapshot() PoolStats {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	stats := p.stats
+	stats.Idle = len(p.idle)
+	return stats
+}
+
+// close closes the ready connections, commands in progress are not interrupted
+func (p *connPool) close() error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.closed {
+		return nil
+	}
+	p.closed = true
+	close(p.ready)
+	for _, conn := range p.idle {
+		_ = conn.client.socket.Close()
+		<-p.slots
+	}
+	p.idle = nil
+	return nil
+}
@@ -0,0 +1,144 @@
+package client
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"net"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// servePID answers every command with a pid after delay, maxOpen tracks the number of open connections,
+// including ready connections waiting for a command
+func servePID(t *testing.T, delay time.Duration) (path string, maxOpen *atomic.Int32) {
+	t.Helper()
+	path = filepath.Join(t.TempDir(), "nsd.sock")
+	l, err := net.Listen("unix", path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = l.Close() })
+
+	var open atomic.Int32
+	maxOpen = new(atomic.Int32)
+	go func() {
+		for {
+			conn, err := l.Accept()
+			if err != nil {
+				return
+			}
+			n := open.Add(1)
+			for {
+				m := maxOpen.Load()
+				if n <= m || maxOpen.CompareAndSwap(m, n) {
+					break
+				}
+			}
+			go func() {
+				defer open.Add(-1)
+				defer func() { _ = conn.Close() }()
+				if bufio.NewScanner(conn).Scan() {
+					time.Sleep(delay)
+					_, _ = conn.Write([]byte("1234\n"))
+				}
+			}()
+		}
+	}()
+	return path, maxOpen
+}
+
+func TestNewPooledUNIXSocketClient(t *testing.T) {
+	path, maxOpen := servePID(t, time.Millisecond)
+
+	c := NewPooledUNIXSocketClient(path, PoolConfig{MaxConns: 3, MaxIdle: 2})
+	defer func() { _ = c.Close() }()
+
+	var wg sync.WaitGroup
+	for i := 0; i < 50; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if pid, err := c.ServerPID(); err != nil || pid != 1234 {
+				t.Errorf("ServerPID() = %v, %v", pid, err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	if m := maxOpen.Load(); m > 3 {
+		t.Errorf("max open connections = %d, want <= 3", m)
+	}
+	stats := c.PoolStats()
+	if stats.Active != 0 || stats.Hits+stats.Misses != 50 || stats.DialErrors != 0 {
+		t.Errorf("PoolStats() = %+v", stats)
+	}
+
+	// Sequential commands use the ready connections opened after the previous command
+	for i := 0; i < 5; i++ {
+		time.Sleep(10 * time.Millisecond)
+		if _, err := c.ServerPID(); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if hits := c.PoolStats().Hits - stats.Hits; hits != 5 {
+		t.Errorf("PoolStats().Hits increased by %d, want 5", hits)
+	}
+
+	if err := c.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := c.ServerPID(); err != ErrClientClosed {
+		t.Errorf("ServerPID() error = %v, want %v", err, ErrClientClosed)
+	}
+}
+
+func TestNewPooledUNIXSocketClient_singleConn(t *testing.T) {
+	path, maxOpen := servePID(t, 0)
+	c := NewPooledUNIXSocketClient(path, PoolConfig{MaxConns: 1})
+	defer func() { _ = c.Close() }()
+
+	// The ready connection opened after a command holds the only slot, the next command must take it
+	start := time.Now()
+	for i := 0; i < 20; i++ {
+		if _, err := c.ServerPIDContext(context.Background()); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("20 commands took %v", elapsed)
+	}
+	if m := maxOpen.Load(); m > 1 {
+		t.Errorf("max open connections = %d, want 1", m)
+	}
+}
+
+func TestNewPooledUNIXSocketClient_waitTimeout(t *testing.T) {
+	path, _ := servePID(t, 500*time.Millisecond)
+	c := NewPooledUNIXSocketClient(path, PoolConfig{MaxConns: 1, MaxIdle: -1})
+	defer func() { _ = c.Close() }()
+	c.SetTimeout(50 * time.Millisecond)
+
+	// The deadline of the context replaces the timeout for the command holding the slot
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	busy := make(chan error)
+	go func() {
+		_, err := c.ServerPIDContext(ctx)
+		busy <- err
+	}()
+	for c.PoolStats().Active == 0 {
+		time.Sleep(time.Millisecond)
+	}
+
+	// Waiting for the slot held by the other command is bounded by the client timeout
+	if _, err := c.ServerPID(); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("ServerPID() error = %v, want %v", err, context.DeadlineExceeded)
+	}
+	if err := <-busy; err != nil {
+		t.Errorf("ServerPIDContext() error = %v", err)
+	}
+}
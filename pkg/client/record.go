@@ -0,0 +1,276 @@
+package client
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"net"
+	"os"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Recorder is a Dialer recording every session on the connections of another dialer as a transcript,
+// e.g. to reproduce a failure seen in production with Replayer.
+// TSIG and cookie secrets are redacted from the commands and replies, like in the logs written by SetLogger.
+type Recorder struct {
+	dialer Dialer
+	mu     sync.Mutex
+	w      io.Writer
+	err    error
+}
+
+// NewRecorder returns a dialer recording the sessions of dialer to w in the text format of Transcript.
+// Each session is written once its connection is closed.
+func NewRecorder(dialer Dialer, w io.Writer) *Recorder {
+	return &Recorder{dialer: dialer, w: w}
+}
+
+func (r *Recorder) Dial(ctx context.Context) (io.ReadWriteCloser, error) {
+	start := time.Now()
+	conn, err := r.dialer.Dial(ctx)
+	if err != nil {
+		return nil, err
+	}
+	recording := &recordingConn{ReadWriteCloser: conn, recorder: r, start: start}
+	if netConn, ok := conn.(net.Conn); ok {
+		// Keep deadlines working and the remote address available to observers
+		return &recordingNetConn{Conn: netConn, recordingConn: recording}, nil
+	}
+	return recording, nil
+}
+
+// Err returns the first error writing the transcript, sessions are no longer recorded after it
+func (r *Recorder) Err() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.err
+}
+
+func (r *Recorder) record(session Session) {
+	var b strings.Builder
+	session.write(&b)
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.err == nil {
+		_, r.err = io.WriteString(r.w, b.String())
+	}
+}
+
+// recordingConn keeps the bytes sent and received until the connection is closed
+type recordingConn struct {
+	io.ReadWriteCloser
+	recorder *Recorder
+	start    time.Time
+
+	// mu guards the buffers, the reply is read concurrently with sending the zones of bulk commands
+	mu       sync.Mutex
+	sent     bytes.Buffer
+	received bytes.Buffer
+	// eof is set when the server closed the connection
+	eof    bool
+	closed bool
+}
+
+func (c *recordingConn) Read(p []byte) (int, error) {
+	n, err := c.ReadWriteCloser.Read(p)
+	c.mu.Lock()
+	c.received.Write(p[:n])
+	// Errors of the client closing the connection or giving up waiting are not the end of the reply
+	if err != nil && !errors.Is(err, net.ErrClosed) && !errors.Is(err, io.ErrClosedPipe) &&
+		!errors.Is(err, os.ErrDeadlineExceeded) {
+		c.eof = true
+	}
+	c.mu.Unlock()
+	return n, err
+}
+
+func (c *recordingConn) Write(p []byte) (int, error) {
+	n, err := c.ReadWriteCloser.Write(p)
+	c.mu.Lock()
+	c.sent.Write(p[:n])
+	c.mu.Unlock()
+	return n, err
+}
+
+func (c *recordingConn) Close() error {
+	err := c.ReadWriteCloser.Close()
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.closed {
+		return err
+	}
+	c.closed = true
+	if session, ok := recordedSession(c.sent.String(), c.received.String(), c.eof); ok {
+		session.Time = c.start.UTC()
+		session.Duration = time.Since(c.start)
+		c.recorder.record(session)
+	}
+	return err
+}
+
+// recordingNetConn is a recordingConn of a net.Conn
+type recordingNetConn struct {
+	net.Conn
+	*recordingConn
+}
+
+func (c *recordingNetConn) Read(p []byte) (int, error)  { return c.recordingConn.Read(p) }
+func (c *recordingNetConn) Write(p []byte) (int, error) { return c.recordingConn.Write(p) }
+func (c *recordingNetConn) Close() error                { return c.recordingConn.Close() }
+
+// recordedSession splits the bytes of a connection into a session with secrets redacted,
+// eof tells whether the server closed the connection. It reports false if no command was sent.
+func recordedSession(sent string, received string, eof bool) (Session, bool) {
+	request, ok := strings.CutPrefix(sent, headerVersion)
+	if !ok || request == "" {
+		return Session{}, false
+	}
+	lines := strings.Split(strings.TrimSuffix(request, "\n"), "\n")
+	session := Session{Command: redactCmd(lines[0])}
+	for _, line := range lines[1:] {
+		if line != endOfTransmission {
+			session.Input = append(session.Input, line)
+		}
+	}
+
+	// The reply ends with an empty line, or when the server closes the connection
+	for received != "" {
+		line, rest, found := strings.Cut(received, "\n")
+		if found && line == "" {
+			session.End = ReplyEndEmptyLine
+			return session, true
+		}
+		session.Reply = append(session.Reply, redactReplyLine(line))
+		if !found {
+			session.Cut = true
+			break
+		}
+		received = rest
+	}
+	if !eof {
+		session.End = ReplyEndStalled
+	}
+	return session, true
+}
+
+// Replayer is a Dialer replaying the sessions of a transcript over in-memory connections.
+// Every connection is answered with the reply of the first unused session with the same command and input,
+// ended like it was recorded, connections with commands not in the transcript are closed without a reply.
+// Commands with secrets also match their redacted form, so transcripts written by Recorder can be replayed.
+type Replayer struct {
+	mu        sync.Mutex
+	sessions  []Session
+	used      []bool
+	unmatched []Session
+}
+
+// NewReplayer returns a dialer replaying the sessions of t
+func NewReplayer(t *Transcript) *Replayer {
+	return &Replayer{
+		sessions: t.Sessions,
+		used:     make([]bool, len(t.Sessions)),
+	}
+}
+
+func (r *Replayer) Dial(ctx context.Context) (io.ReadWriteCloser, error) {
+	clientConn, serverConn := net.Pipe()
+	go r.serve(serverConn)
+	return clientConn, nil
+}
+
+// replayTimeout limits how long a connection is served, so an abandoned client doesn't leak the goroutine
+const replayTimeout = 10 * time.Second
+
+func (r *Replayer) serve(conn net.Conn) {
+	defer func() { _ = conn.Close() }()
+	_ = conn.SetDeadline(time.Now().Add(replayTimeout))
+	request, ok := readSession(conn)
+	if !ok {
+		return
+	}
+
+	session, found := r.match(request)
+	if !found {
+		return
+	}
+	w := bufio.NewWriter(conn)
+	for i, line := range session.Reply {
+		_, _ = w.WriteString(line)
+		if !session.Cut || i < len(session.Reply)-1 {
+			_, _ = w.WriteString("\n")
+		}
+	}
+	if session.End == ReplyEndEmptyLine {
+		_, _ = w.WriteString("\n")
+	}
+	_ = w.Flush()
+	if session.End == ReplyEndStalled {
+		// Keep the connection open until the client gives up
+		_, _ = io.Copy(io.Discard, conn)
+	}
+}
+
+// match marks the first unused session matching the request as used and returns it
+func (r *Replayer) match(request Session) (Session, bool) {
+	redactedCmd := redactCmd(request.Command)
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for i, session := range r.sessions {
+		if r.used[i] || !slices.Equal(session.Input, request.Input) {
+			continue
+		}
+		if session.Command == request.Command || session.Command == redactedCmd {
+			r.used[i] = true
+			return session, true
+		}
+	}
+	r.unmatched = append(r.unmatched, request)
+	return Session{}, false
+}
+
+// readSession reads the command and input sent by the client
+func readSession(r io.Reader) (Session, bool) {
+	scanner := bufio.NewScanner(r)
+	if !scanner.Scan() {
+		return Session{}, false
+	}
+	cmd, ok := strings.CutPrefix(scanner.Text(), headerVersion)
+	if !ok {
+		return Session{}, false
+	}
+	session := Session{Command: cmd}
+	if name, _, _ := strings.Cut(cmd, " "); name == cmdAddZones || name == cmdDelZones {
+		for scanner.Scan() && scanner.Text() != endOfTransmission {
+			session.Input = append(session.Input, scanner.Text())
+		}
+	}
+	return session, true
+}
+
+// Unmatched returns the commands and input that were not found in the transcript
+func (r *Replayer) Unmatched() []Session {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return slices.Clone(r.unmatched)
+}
+
+// Unused returns the sessions of the transcript that have not been replayed
+func (r *Replayer) Unused() []Session {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var unused []Session
+	for i, session := range r.sessions {
+		if !r.used[i] {
+			unused = append(unused, session)
+		}
+	}
+	return unused
+}
@@ -0,0 +1,202 @@
+package client_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"nsd/pkg/client"
+	"nsd/pkg/client/nsdtest"
+)
+
+func TestReplayer(t *testing.T) {
+	transcript, err := client.ReadTranscript(strings.NewReader(testTranscript))
+	if err != nil {
+		t.Fatal(err)
+	}
+	replayer := client.NewReplayer(transcript)
+	c := client.New(replayer)
+	defer func() { _ = c.Close() }()
+
+	status, err := c.ZoneStatus("example.com")
+	if err != nil || status.State != client.ZoneStatePrimary {
+		t.Errorf("ZoneStatus() = %+v, %v", status, err)
+	}
+	// Each session is replayed once
+	if _, err := c.ZoneStatus("example.com"); err == nil {
+		t.Errorf("ZoneStatus() expected error")
+	}
+	if unmatched := replayer.Unmatched(); len(unmatched) != 1 || unmatched[0].Command != "zonestatus example.com" {
+		t.Errorf("Unmatched() = %+v", unmatched)
+	}
+	if unused := replayer.Unused(); len(unused) != 1 || unused[0].Command != "addzones" {
+		t.Errorf("Unused() = %+v", unused)
+	}
+
+	_, err = c.AddZones([]client.ZonePattern{{Zone: "example.net", Pattern: "replica"}, {Zone: "example.com", Pattern: "replica"}})
+	if !errors.Is(err, client.ErrZoneExists) {
+		t.Errorf("AddZones() error = %v, want %v", err, client.ErrZoneExists)
+	}
+	if unused := replayer.Unused(); len(unused) != 0 {
+		t.Errorf("Unused() = %+v", unused)
+	}
+}
+
+func TestRecorder(t *testing.T) {
+	server := nsdtest.NewUNIXServer(nil)
+	defer server.Close()
+
+	type results struct {
+		zoneStatus *client.ZoneStatus
+		keyNames   []string
+		added      *client.ZoneOperationResult
+		notFound   error
+	}
+	const secret = "K2tf3TRjvQkVCmJF3/Z9vA=="
+	// NSD echoes a secret it cannot decode in the error reply
+	const invalidSecret = "K2tf3TRjvQkVCmJF3/Z9vA="
+	// run sends the same commands to the server and to the replayed transcript, it returns the secrets it has seen
+	run := func(c *client.Client) (r results, secrets []string) {
+		t.Helper()
+		var err error
+		if r.zoneStatus, err = c.ZoneStatus("example.org"); err != nil {
+			t.Fatal(err)
+		}
+		keys, err := c.GetTSigs()
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, key := range keys {
+			r.keyNames = append(r.keyNames, key.Name)
+			secrets = append(secrets, key.Secret)
+		}
+		if err := c.AddTSig("key3", secret, nil); err != nil {
+			t.Fatal(err)
+		}
+		var serverErr *client.ServerError
+		if _, err := c.Do(context.Background(), "add_tsig", "key4", invalidSecret); !errors.As(err, &serverErr) {
+			t.Fatalf("Do() error = %v, want ServerError", err)
+		}
+		cookies, err := c.GetCookieSecrets()
+		if err != nil {
+			t.Fatal(err)
+		}
+		secrets = append(secrets, cookies.Active)
+		r.added, _ = c.AddZones([]client.ZonePattern{{Zone: "example.net", Pattern: "replica"}, {Zone: "example.com", Pattern: "replica"}})
+		_, r.notFound = c.ZoneStatus("example.info")
+		return r, append(secrets, secret, invalidSecret)
+	}
+
+	var recording bytes.Buffer
+	recorder := client.NewRecorder(server.Dialer(), &recording)
+	recorded, secrets := run(client.New(recorder))
+	if err := recorder.Err(); err != nil {
+		t.Fatal(err)
+	}
+	for _, secret := range secrets {
+		if strings.Contains(recording.String(), secret) {
+			t.Errorf("secret %q recorded in:\n%s", secret, recording.String())
+		}
+	}
+
+	transcript, err := client.ReadTranscript(&recording)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(transcript.Sessions) != 7 {
+		t.Fatalf("recorded %d sessions, want 7", len(transcript.Sessions))
+	}
+	for _, session := range transcript.Sessions {
+		if session.Time.IsZero() || session.Duration <= 0 {
+			t.Errorf("session %q recorded without time", session.Command)
+		}
+	}
+	if input := transcript.Sessions[5].Input; !reflect.DeepEqual(input, []string{"example.net replica", "example.com replica"}) {
+		t.Errorf("addzones input = %q", input)
+	}
+
+	replayer := client.NewReplayer(transcript)
+	replayed, _ := run(client.New(replayer))
+	if !reflect.DeepEqual(replayed, recorded) {
+		t.Errorf("replayed %+v, want %+v", replayed, recorded)
+	}
+	if !errors.Is(replayed.notFound, client.ErrZoneNotFound) {
+		t.Errorf("ZoneStatus() error = %v, want %v", replayed.notFound, client.ErrZoneNotFound)
+	}
+	if unused := replayer.Unused(); len(unused) != 0 {
+		t.Errorf("Unused() = %+v", unused)
+	}
+}
+
+func TestRecorder_faults(t *testing.T) {
+	server := nsdtest.NewUNIXServer(nil)
+	defer server.Close()
+
+	tests := []struct {
+		name    string
+		chaos   nsdtest.Chaos
+		wantCut bool
+		wantEnd client.ReplyEnd
+		wantErr error
+	}{
+		{
+			name:    "truncate",
+			chaos:   nsdtest.Chaos{Truncate: 1},
+			wantCut: true,
+			wantEnd: client.ReplyEndClosed,
+			wantErr: io.ErrUnexpectedEOF,
+		},
+		{
+			name:    "unterminated",
+			chaos:   nsdtest.Chaos{Unterminated: 1},
+			wantEnd: client.ReplyEndStalled,
+			wantErr: context.DeadlineExceeded,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.chaos.Seed = 1
+			// run sends the same command to the server and to the replayed transcript
+			run := func(dialer client.Dialer) error {
+				t.Helper()
+				c := client.New(dialer)
+				defer func() { _ = c.Close() }()
+				ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+				defer cancel()
+				_, err := c.StatusContext(ctx)
+				if !errors.Is(err, tt.wantErr) {
+					t.Fatalf("Status() error = %v, want %v", err, tt.wantErr)
+				}
+				return err
+			}
+
+			var recording bytes.Buffer
+			recorder := client.NewRecorder(nsdtest.NewChaosDialer(server.Dialer(), tt.chaos), &recording)
+			recorded := run(recorder)
+			if err := recorder.Err(); err != nil {
+				t.Fatal(err)
+			}
+
+			transcript, err := client.ReadTranscript(&recording)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(transcript.Sessions) != 1 {
+				t.Fatalf("recorded %d sessions, want 1", len(transcript.Sessions))
+			}
+			if session := transcript.Sessions[0]; session.Cut != tt.wantCut || session.End != tt.wantEnd {
+				t.Errorf("recorded session %+v, want cut %v and end %q", session, tt.wantCut, tt.wantEnd)
+			}
+
+			replayed := run(client.NewReplayer(transcript))
+			if replayed.Error() != recorded.Error() {
+				t.Errorf("replayed error %v, want %v", replayed, recorded)
+			}
+		})
+	}
+}
@@ -0,0 +1,111 @@
+package client
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// Reply is the reply to a command sent with Do
+type Reply struct {
+	// Lines are the lines sent by the server, without the terminating blank line
+	Lines []string
+}
+
+// OK reports whether the server replied with ok, possibly followed by details such as "ok, 3 zones"
+func (r *Reply) OK() bool {
+	return len(r.Lines) > 0 && (r.Lines[0] == replyOK || strings.HasPrefix(r.Lines[0], replyOK+","))
+}
+
+// Err returns the first error line as a ServerError, or nil if the server didn't reply with an error
+func (r *Reply) Err() error {
+	for _, line := range r.Lines {
+		if strings.HasPrefix(line, replyError) {
+			return newServerError(line)
+		}
+	}
+	return nil
+}
+
+// Value returns the value of the first "key: value" line with the given key
+func (r *Reply) Value(key string) (string, bool) {
+	for _, line := range r.Lines {
+		if match := commonKeyValueRegex.FindStringSubmatch(line); match != nil && match[commonKeyValueRegex.SubexpIndex("key")] == key {
+			return match[commonKeyValueRegex.SubexpIndex("value")], true
+		}
+	}
+	return "", false
+}
+
+// Values returns the values of all "key: value" lines with the given key, in reply order
+func (r *Reply) Values(key string) []string {
+	var values []string
+	for _, line := range r.Lines {
+		if match := commonKeyValueRegex.FindStringSubmatch(line); match != nil && match[commonKeyValueRegex.SubexpIndex("key")] == key {
+			values = append(values, match[commonKeyValueRegex.SubexpIndex("value")])
+		}
+	}
+	return values
+}
+
+// KeyValues returns the "key: value" lines as a map, the last line wins for repeated keys.
+// Lines that are not key/value pairs are ignored.
+func (r *Reply) KeyValues() map[string]string {
+	values := make(map[string]string)
+	for _, line := range r.Lines {
+		if match := commonKeyValueRegex.FindStringSubmatch(line); match != nil {
+			values[match[commonKeyValueRegex.SubexpIndex("key")]] = match[commonKeyValueRegex.SubexpIndex("value")]
+		}
+	}
+	return values
+}
+
+var rawCmdRegex = regexp.MustCompile(`^[a-z][a-z0-9_]*$`)
+
+// validateRawCmd rejects commands and arguments that would be split or merged differently by the server
+func validateRawCmd(cmd string, args []string) error {
+	if !rawCmdRegex.MatchString(cmd) {
+		return fmt.Errorf("invalid command: %q", cmd)
+	}
+	if cmd == cmdAddZones || cmd == cmdDelZones {
+		// The server reads zones until the end of transmission, which Do doesn't send
+		return fmt.Errorf("%s reads zones from the connection, use AddZones or DelZones", cmd)
+	}
+	for _, arg := range args {
+		if err := validateBulkArg(arg); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Do sends a control command that may not be wrapped by this package, e.g. Do(ctx, "zonestatus", "example.com").
+// The command and arguments are sent as is, separated by spaces, and must not contain whitespace.
+// An error reply is returned both in the Reply and as a ServerError.
+// Commands sent with Do are never retried, as they may change the server state.
+func (c *Client) Do(ctx context.Context, cmd string, args ...string) (_ *Reply, err error) {
+	if err := validateRawCmd(cmd, args); err != nil {
+		return nil, err
+	}
+
+	c, err = c.begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer c.end(ctx, &err)
+
+	if err := c.sendCmd(strings.Join(append([]string{cmd}, args...), " ")); err != nil {
+		return nil, err
+	}
+	return parseRawReply(c)
+}
+
+func parseRawReply(c replyReader) (*Reply, error) {
+	lines, err := c.readReply()
+	if err != nil {
+		return nil, err
+	}
+	reply := &Reply{Lines: lines}
+	return reply, reply.Err()
+}
@@ -0,0 +1,108 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"net"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func Test_parseRawReply(t *testing.T) {
+	tests := []struct {
+		name       string
+		c          replyReader
+		wantOK     bool
+		wantErr    error
+		wantValues map[string]string
+	}{
+		{
+			name:       "ok",
+			c:          NewStaticReply([]string{"ok"}),
+			wantOK:     true,
+			wantValues: map[string]string{},
+		},
+		{
+			name:       "ok with details",
+			c:          NewStaticReply([]string{"ok, 3 zones"}),
+			wantOK:     true,
+			wantValues: map[string]string{},
+		},
+		{
+			name:       "key values",
+			c:          NewStaticReply([]string{"version: 4.11.0", "verbosity: 2", "not a pair"}),
+			wantValues: map[string]string{"version": "4.11.0", "verbosity": "2"},
+		},
+		{
+			name:       "error",
+			c:          NewStaticReply([]string{"error zone example.net not configured"}),
+			wantErr:    ErrZoneNotFound,
+			wantValues: map[string]string{},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parseRawReply(tt.c)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("parseRawReply() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got.OK() != tt.wantOK {
+				t.Errorf("OK() = %v, want %v", got.OK(), tt.wantOK)
+			}
+			if !errors.Is(got.Err(), tt.wantErr) {
+				t.Errorf("Err() = %v, want %v", got.Err(), tt.wantErr)
+			}
+			if values := got.KeyValues(); !reflect.DeepEqual(values, tt.wantValues) {
+				t.Errorf("KeyValues() = %v, want %v", values, tt.wantValues)
+			}
+		})
+	}
+}
+
+func TestReply_Values(t *testing.T) {
+	reply := &Reply{Lines: []string{"zone: example.com", "state: ok", "zone: example.net", "state: refreshing"}}
+	if got := reply.Values("zone"); !reflect.DeepEqual(got, []string{"example.com", "example.net"}) {
+		t.Errorf("Values() = %v", got)
+	}
+	if got, ok := reply.Value("state"); !ok || got != "ok" {
+		t.Errorf("Value() = %v, %v", got, ok)
+	}
+	if _, ok := reply.Value("pattern"); ok {
+		t.Error("Value() found a missing key")
+	}
+}
+
+func TestClient_Do(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "nsd.sock")
+	l, err := net.Listen("unix", path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = l.Close() })
+	serveOneCommandPerConn(t, l, map[string]string{
+		"zonestatus example.com": "zone: example.com\n\tstate: ok\n",
+	})
+
+	c := NewDialingUNIXSocketClient(path)
+	defer func() { _ = c.Close() }()
+
+	reply, err := c.Do(context.Background(), "zonestatus", "example.com")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if state, _ := reply.Value("state"); state != "ok" {
+		t.Errorf("Do() state = %q", state)
+	}
+
+	var serverErr *ServerError
+	if _, err := c.Do(context.Background(), "newcommand"); !errors.As(err, &serverErr) {
+		t.Errorf("Do() error = %v, want a ServerError", err)
+	}
+
+	for _, args := range [][]string{{"zone status"}, {""}, {"reload", "example.com\nstop"}, {"addzones"}} {
+		if _, err := c.Do(context.Background(), args[0], args[1:]...); err == nil {
+			t.Errorf("Do(%q) accepted an invalid command", args)
+		}
+	}
+}
@@ -0,0 +1,114 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"io"
+	"math/rand/v2"
+	"net"
+	"syscall"
+	"time"
+)
+
+// RetryPolicy retries idempotent commands, such as status and zonestatus, that failed because of the connection.
+// Commands changing the server state, such as addzone or stop, are never retried.
+// Retries are only possible on dialing and pooled clients, which reconnect for every attempt.
+type RetryPolicy struct {
+	// MaxAttempts is the total number of attempts, including the first
+	MaxAttempts int
+	// InitialBackoff is the wait before the first retry
+	InitialBackoff time.Duration
+	// MaxBackoff limits the wait between retries
+	MaxBackoff time.Duration
+	// Multiplier increases the wait after each retry
+	Multiplier float64
+	// Jitter randomizes each wait by up to this fraction, between 0 and 1
+	Jitter float64
+	// Retryable decides whether an error is retried, defaults to IsConnectionError
+	Retryable func(err error) bool
+}
+
+// DefaultRetryPolicy retries for about a second, long enough to ride out a reset connection but not a restart
+var DefaultRetryPolicy = RetryPolicy{
+	MaxAttempts:    4,
+	InitialBackoff: 100 * time.Millisecond,
+	MaxBackoff:     2 * time.Second,
+	Multiplier:     2,
+	Jitter:         0.2,
+}
+
+// SetRetryPolicy sets the retry policy for idempotent commands, nil disables retries.
+// It must not be called concurrently with commands.
+func (c *Client) SetRetryPolicy(policy *RetryPolicy) {
+	c.retry = policy
+}
+
+// IsConnectionError reports whether err was caused by connecting to or talking to the server,
+// as opposed to an error reply from the server.
+// Timeouts are only connection errors when dialing, a command that exceeded its deadline is not.
+func IsConnectionError(err error) bool {
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return false
+	}
+	var netErr net.Error
+	if errors.As(err, &netErr) && netErr.Timeout() {
+		var opErr *net.OpError
+		return errors.As(err, &opErr) && opErr.Op == "dial"
+	}
+	return errors.As(err, &netErr) ||
+		errors.Is(err, io.EOF) ||
+		errors.Is(err, io.ErrUnexpectedEOF) ||
+		errors.Is(err, syscall.ECONNREFUSED) ||
+		errors.Is(err, syscall.ECONNRESET) ||
+		errors.Is(err, syscall.EPIPE) ||
+		// The unix socket is missing while NSD restarts
+		errors.Is(err, syscall.ENOENT)
+}
+
+// backoff returns the wait before the given retry, starting at 1
+func (p *RetryPolicy) backoff(retry int) time.Duration {
+	d := float64(p.InitialBackoff)
+	for i := 1; i < retry; i++ {
+		d *= max(p.Multiplier, 1)
+	}
+	if p.MaxBackoff > 0 {
+		d = min(d, float64(p.MaxBackoff))
+	}
+	if p.Jitter > 0 {
+		d += d * min(p.Jitter, 1) * (2*rand.Float64() - 1)
+	}
+	return time.Duration(d)
+}
+
+// withRetry runs an idempotent command according to the client's retry policy
+func withRetry[T any](ctx context.Context, c *Client, f func(ctx context.Context) (T, error)) (T, error) {
+	policy := c.retry
+	if policy == nil || (c.dial == nil && c.pool == nil) {
+		// A client with a single connection can't reconnect
+		return f(ctx)
+	}
+	retryable := policy.Retryable
+	if retryable == nil {
+		retryable = IsConnectionError
+	}
+
+	for attempt := 1; ; attempt++ {
+		v, err := f(ctx)
+		if err == nil || attempt >= policy.MaxAttempts || !retryable(err) {
+			return v, err
+		}
+
+		wait := policy.backoff(attempt)
+		if c.logger != nil {
+			c.logger.DebugContext(ctx, "nsd control retry", "attempt", attempt, "wait", wait,
+				"error", redactReplyLine(err.Error()))
+		}
+		timer := time.NewTimer(wait)
+		select {
+		case <-timer.C:
+		case <-ctx.Done():
+			timer.Stop()
+			return v, err
+		}
+	}
+}
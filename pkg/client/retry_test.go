@@ -0,0 +1,106 @@
+package client
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"log/slog"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"testing"
+	"time"
+)
+
+func TestClient_retry(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "nsd.sock")
+	c := NewDialingUNIXSocketClient(path)
+	c.SetRetryPolicy(&RetryPolicy{
+		MaxAttempts:    20,
+		InitialBackoff: 20 * time.Millisecond,
+		MaxBackoff:     50 * time.Millisecond,
+		Multiplier:     2,
+		Jitter:         0.5,
+	})
+
+	// Mutating commands fail right away while the socket is missing
+	start := time.Now()
+	if err := c.LogReopen(); err == nil || !IsConnectionError(err) {
+		t.Fatalf("LogReopen() error = %v, want connection error", err)
+	} else if time.Since(start) > 20*time.Millisecond {
+		t.Errorf("LogReopen() was retried")
+	}
+
+	// The server comes up while the idempotent command is retried
+	time.AfterFunc(100*time.Millisecond, func() {
+		l, err := net.Listen("unix", path)
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		t.Cleanup(func() { _ = l.Close() })
+		serveOneCommandPerConn(t, l, map[string]string{cmdServerPID: "1234\n"})
+	})
+	if pid, err := c.ServerPID(); err != nil || pid != 1234 {
+		t.Errorf("ServerPID() = %v, %v", pid, err)
+	}
+}
+
+func TestIsConnectionError(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want bool
+	}{
+		{name: "reset", err: &net.OpError{Op: "read", Net: "unix", Err: syscall.ECONNRESET}, want: true},
+		{name: "refused", err: &net.OpError{Op: "dial", Net: "unix", Err: syscall.ECONNREFUSED}, want: true},
+		{name: "eof", err: io.EOF, want: true},
+		{name: "dial timeout", err: &net.OpError{Op: "dial", Net: "tcp", Err: os.ErrDeadlineExceeded}, want: true},
+		{name: "command timeout", err: &net.OpError{Op: "read", Net: "tcp", Err: os.ErrDeadlineExceeded}, want: false},
+		{name: "pipe timeout", err: os.ErrDeadlineExceeded, want: false},
+		{name: "context deadline", err: context.DeadlineExceeded, want: false},
+		{name: "server error", err: newServerError("error zone example.net not configured"), want: false},
+	}
+	for _, tt := range tests {
+		if got := IsConnectionError(tt.err); got != tt.want {
+			t.Errorf("IsConnectionError(%s) = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestRetryPolicy_backoff(t *testing.T) {
+	policy := RetryPolicy{
+		InitialBackoff: 100 * time.Millisecond,
+		MaxBackoff:     time.Second,
+		Multiplier:     2,
+	}
+	want := []time.Duration{100 * time.Millisecond, 200 * time.Millisecond, 400 * time.Millisecond, 800 * time.Millisecond, time.Second}
+	for i, w := range want {
+		if got := policy.backoff(i + 1); got != w {
+			t.Errorf("backoff(%d) = %v, want %v", i+1, got, w)
+		}
+	}
+}
+
+func TestClient_retryLogRedacted(t *testing.T) {
+	const secret = "K2tf3TRjvQkVCmJF3/Z9vA=="
+	c := New(pipeDialer(map[string]string{
+		cmdPrintTsig: `key: name: "key1" secret: "` + secret + `" algorithm:` + "\n",
+	}))
+	c.SetRetryPolicy(&RetryPolicy{MaxAttempts: 2, Retryable: func(err error) bool { return true }})
+	var buf bytes.Buffer
+	c.SetLogger(slog.New(slog.NewTextHandler(&buf, &slog.HandlerOptions{Level: slog.LevelDebug})))
+
+	if _, err := c.GetTSigs(); err == nil {
+		t.Fatal("GetTSigs() succeeded")
+	}
+	logs := buf.String()
+	if !strings.Contains(logs, `msg="nsd control retry"`) {
+		t.Errorf("logs don't contain the retry:\n%s", logs)
+	}
+	if strings.Contains(logs, secret) {
+		t.Errorf("logs contain the TSIG secret:\n%s", logs)
+	}
+}
@@ -1,5 +1,12 @@
 package client
 
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+)
+
 /*
 The NSD server daemon can be controlled either via an local unix socket common located at `/var/run/nsd.sock`,
 or via
@@ -20,6 +27,8 @@ Commands can be found in the NSD source code: https://github.com/NLnetLabs/nsd/b
 //goland:noinspection SpellCheckingInspection
 const (
 	headerVersion = "NSDCT1 "
+	// Terminates the list of zones sent after addzones and delzones
+	endOfTransmission = "\x04"
 
 	cmdActivateCookieSecret = "activate_cookie_secret"
 	cmdAddCookieSecret      = "add_cookie_secret"
@@ -39,8 +48,8 @@ const (
 	cmdPrintTsig            = "print_tsig"
 	// Same as repattern
 	cmdReconfig = "reconfig"
-	// Can be suffixed with a space separated list of zones to reload from disk,
-	// if no zones are given all will be reloaded
+	// Can be suffixed with a single zone to reload from disk,
+	// if no zone is given all will be reloaded
 	cmdReload = "reload"
 	// Reload the configuration file if possible and apply keys and pattern anew
 	cmdRepattern    = "repattern"
@@ -67,8 +76,70 @@ type CookieSecrets struct {
 	Staging *string
 }
 
-type ZoneStatus struct {
-	Zone       string
-	State      string
-	Attributes map[string]string
+// ZoneOperationResult is the outcome of a command operating on a list of zones
+type ZoneOperationResult struct {
+	// All is set if the command was applied to all zones
+	All bool
+	// Succeeded lists the requested zones that the command was applied to
+	Succeeded []string
+	// Failed maps zones to the error message the server replied with.
+	// A zone in neither Succeeded nor Failed may have failed, as the server replied with an error naming no zone.
+	Failed map[string]string
+}
+
+// Err returns an error describing the failed zones, or nil if no zones failed
+func (r *ZoneOperationResult) Err() error {
+	if len(r.Failed) == 0 {
+		return nil
+	}
+	zones := make([]string, 0, len(r.Failed))
+	for zone := range r.Failed {
+		zones = append(zones, zone)
+	}
+	sort.Strings(zones)
+	errs := make([]error, 0, len(zones))
+	for _, zone := range zones {
+		errs = append(errs, newServerError(r.Failed[zone]))
+	}
+	return fmt.Errorf("operation failed for zones: %s: %w", strings.Join(zones, ", "), errors.Join(errs...))
+}
+
+func (r *ZoneOperationResult) failed(zone string) bool {
+	for failed := range r.Failed {
+		if normalizeZone(failed) == normalizeZone(zone) {
+			return true
+		}
+	}
+	return false
+}
+
+// normalizeZone makes zone names comparable regardless of case and trailing dot
+func normalizeZone(zone string) string {
+	return strings.ToLower(strings.TrimSuffix(zone, "."))
+}
+
+// ZonePattern is a zone and the name of the pattern it is configured from
+type ZonePattern struct {
+	Zone    string
+	Pattern string
+}
+
+// ReconfigReport is the outcome of reloading the NSD config file
+type ReconfigReport struct {
+	// OK is set if the new config was read and applied
+	OK bool
+	// ConfigFile is the path of the config file NSD read
+	ConfigFile string
+	// Errors contains the errors NSD reported while reading the config file
+	Errors []string
+}
+
+// Err returns an error describing the config errors, or nil if the config was applied
+func (r *ReconfigReport) Err() error {
+	if len(r.Errors) > 0 {
+		return fmt.Errorf("config rejected: %w", newServerError(strings.Join(r.Errors, "; ")))
+	} else if !r.OK {
+		return fmt.Errorf("config not applied")
+	}
+	return nil
 }
@@ -0,0 +1,272 @@
+package client
+
+import (
+	"fmt"
+	"iter"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Stats are the statistics counters reported by NSD.
+// Counters not listed for a server are zero, NSD omits most zero valued counters.
+// Keys printed by NSD: https://github.com/NLnetLabs/nsd/blob/NSD_4_11_0_REL/remote.c#L1266
+type Stats struct {
+	// ServerQueries is the number of queries handled by each server process, indexed by server number
+	ServerQueries []uint64
+	// Queries is the total number of queries handled
+	Queries uint64
+
+	// TimeBoot is the time since the server was started
+	TimeBoot time.Duration
+	// TimeElapsed is the time since the statistics were last reset
+	TimeElapsed time.Duration
+
+	Memory MemoryStats
+
+	// QueryTypes counts queries by query type, e.g. "A" or "TYPE65534"
+	QueryTypes map[string]uint64
+	// Opcodes counts queries by opcode, e.g. "QUERY"
+	Opcodes map[string]uint64
+	// Classes counts queries by class, e.g. "IN"
+	Classes map[string]uint64
+	// Rcodes counts answers by rcode, e.g. "NOERROR"
+	Rcodes map[string]uint64
+
+	Transport TransportStats
+
+	// EDNS is the number of queries with EDNS OPT
+	EDNS uint64
+	// EDNSErr is the number of queries with a malformed EDNS OPT
+	EDNSErr uint64
+	// AnswerWithoutAA is the number of answers without the AA flag set
+	AnswerWithoutAA uint64
+	// RxErr is the number of queries that failed to be received
+	RxErr uint64
+	// TxErr is the number of answers that failed to be sent
+	TxErr uint64
+	// RAXFR is the number of AXFR requests from clients
+	RAXFR uint64
+	// RIXFR is the number of IXFR requests from clients
+	RIXFR uint64
+	// Truncated is the number of answers with the TC flag set
+	Truncated uint64
+	// Dropped is the number of queries that were dropped
+	Dropped uint64
+
+	Zones ZoneCountStats
+
+	// Other holds keys not parsed into fields, e.g. per zone statistics
+	Other map[string]string
+}
+
+// MemoryStats are the disk and memory usage of NSD in bytes
+type MemoryStats struct {
+	DBDisk     uint64
+	DBMem      uint64
+	XfrdMem    uint64
+	ConfigDisk uint64
+	ConfigMem  uint64
+}
+
+// TransportStats counts queries by transport
+type TransportStats struct {
+	UDP  uint64
+	UDP6 uint64
+	TCP  uint64
+	TCP6 uint64
+	TLS  uint64
+	TLS6 uint64
+}
+
+// ZoneCountStats counts configured zones
+type ZoneCountStats struct {
+	Primary   uint64
+	Secondary uint64
+}
+
+// maxStatsServers bounds ServerQueries, NSD limits server-count far below this
+const maxStatsServers = 1024
+
+func parseStatsReply(c replyReader) (*Stats, error) {
+	reply, err := c.readReply()
+	if err != nil {
+		return nil, err
+	}
+
+	stats := &Stats{
+		QueryTypes: make(map[string]uint64),
+		Opcodes:    make(map[string]uint64),
+		Classes:    make(map[string]uint64),
+		Rcodes:     make(map[string]uint64),
+		Other:      make(map[string]string),
+	}
+	var parseErr error
+	err = statsSeq(replyLinesOf(reply), func(entry StatsEntry) bool {
+		if err := stats.set(entry.Key, entry.Value); err != nil {
+			parseErr = malformedReply(entry.Key, err, entry.Key+"="+entry.Value)
+			return false
+		}
+		return true
+	})
+	if err != nil {
+		return nil, err
+	} else if parseErr != nil {
+		return nil, parseErr
+	}
+	return stats, nil
+}
+
+// StatsEntry is a single statistics counter, as printed by NSD
+type StatsEntry struct {
+	// Key is the name of the counter, e.g. "num.queries" or "server0.queries"
+	Key string
+	// Value is the unparsed value, an integer or, for the time keys, seconds with a fraction
+	Value string
+}
+
+// statsSeq parses the lines of a stats reply, yielding every entry
+func statsSeq(lines iter.Seq2[string, error], yield func(StatsEntry) bool) error {
+	for line, err := range lines {
+		if err != nil {
+			return err
+		}
+		if strings.HasPrefix(line, replyError) {
+			return newServerError(line)
+		}
+
+		key, value, found := strings.Cut(line, "=")
+		if !found || key == "" {
+			return unexpectedReply(line)
+		}
+		if !yield(StatsEntry{Key: key, Value: value}) {
+			return nil
+		}
+	}
+	return nil
+}
+
+// set parses a stats key into the matching field
+func (s *Stats) set(key string, value string) (err error) {
+	if counter := s.counter(key); counter != nil {
+		*counter, err = strconv.ParseUint(value, 10, 64)
+		return err
+	}
+
+	switch {
+	case key == "time.boot":
+		s.TimeBoot, err = parseStatsTime(value)
+	case key == "time.elapsed":
+		s.TimeElapsed, err = parseStatsTime(value)
+	case strings.HasPrefix(key, "num.type."):
+		err = setStatsMapCounter(s.QueryTypes, strings.TrimPrefix(key, "num.type."), value)
+	case strings.HasPrefix(key, "num.opcode."):
+		err = setStatsMapCounter(s.Opcodes, strings.TrimPrefix(key, "num.opcode."), value)
+	case strings.HasPrefix(key, "num.class."):
+		err = setStatsMapCounter(s.Classes, strings.TrimPrefix(key, "num.class."), value)
+	case strings.HasPrefix(key, "num.rcode."):
+		err = setStatsMapCounter(s.Rcodes, strings.TrimPrefix(key, "num.rcode."), value)
+	case strings.HasPrefix(key, "server") && strings.HasSuffix(key, ".queries"):
+		// e.g. server0.queries
+		n, convErr := strconv.Atoi(strings.TrimSuffix(strings.TrimPrefix(key, "server"), ".queries"))
+		if convErr != nil || n < 0 || n >= maxStatsServers {
+			s.Other[key] = value
+			return nil
+		}
+		if n >= len(s.ServerQueries) {
+			s.ServerQueries = append(s.ServerQueries, make([]uint64, n+1-len(s.ServerQueries))...)
+		}
+		s.ServerQueries[n], err = strconv.ParseUint(value, 10, 64)
+	default:
+		s.Other[key] = value
+	}
+	return err
+}
+
+// counter returns the field for keys with a plain counter value, or nil
+func (s *Stats) counter(key string) *uint64 {
+	switch key {
+	case "num.queries":
+		return &s.Queries
+	case "size.db.disk":
+		return &s.Memory.DBDisk
+	case "size.db.mem":
+		return &s.Memory.DBMem
+	case "size.xfrd.mem":
+		return &s.Memory.XfrdMem
+	case "size.config.disk":
+		return &s.Memory.ConfigDisk
+	case "size.config.mem":
+		return &s.Memory.ConfigMem
+	case "num.udp":
+		return &s.Transport.UDP
+	case "num.udp6":
+		return &s.Transport.UDP6
+	case "num.tcp":
+		return &s.Transport.TCP
+	case "num.tcp6":
+		return &s.Transport.TCP6
+	case "num.tls":
+		return &s.Transport.TLS
+	case "num.tls6":
+		return &s.Transport.TLS6
+	case "num.edns":
+		return &s.EDNS
+	case "num.ednserr":
+		return &s.EDNSErr
+	case "num.answer_wo_aa":
+		return &s.AnswerWithoutAA
+	case "num.rxerr":
+		return &s.RxErr
+	case "num.txerr":
+		return &s.TxErr
+	case "num.raxfr":
+		return &s.RAXFR
+	case "num.rixfr":
+		return &s.RIXFR
+	case "num.truncated":
+		return &s.Truncated
+	case "num.dropped":
+		return &s.Dropped
+	// Older NSD versions use master/slave
+	case "zone.primary", "zone.master":
+		return &s.Zones.Primary
+	case "zone.secondary", "zone.slave":
+		return &s.Zones.Secondary
+	default:
+		return nil
+	}
+}
+
+func setStatsMapCounter(m map[string]uint64, name string, value string) error {
+	v, err := strconv.ParseUint(value, 10, 64)
+	if err != nil {
+		return err
+	}
+	m[name] = v
+	return nil
+}
+
+// parseStatsTime parses seconds with microsecond precision, e.g. "1234.000567"
+func parseStatsTime(value string) (time.Duration, error) {
+	seconds, fraction, _ := strings.Cut(value, ".")
+	s, err := strconv.ParseUint(seconds, 10, 63)
+	if err != nil {
+		return 0, err
+	} else if s >= uint64(math.MaxInt64/time.Second) {
+		return 0, fmt.Errorf("time out of range: %s", value)
+	}
+	d := time.Duration(s) * time.Second
+	if fraction != "" {
+		if len(fraction) > 9 {
+			fraction = fraction[:9]
+		}
+		ns, err := strconv.ParseUint(fraction+strings.Repeat("0", 9-len(fraction)), 10, 64)
+		if err != nil {
+			return 0, err
+		}
+		d += time.Duration(ns)
+	}
+	return d, nil
+}
@@ -0,0 +1,122 @@
+package client
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func Test_parseStatsReply(t *testing.T) {
+	type args struct {
+		c replyReader
+	}
+	tests := []struct {
+		name    string
+		args    args
+		want    *Stats
+		wantErr bool
+	}{
+		{
+			name: "stats",
+			args: args{
+				NewStaticReply(strings.Split(`server0.queries=12
+server1.queries=30
+num.queries=42
+time.boot=3600.500000
+time.elapsed=60.000250
+size.db.disk=0
+size.db.mem=5000
+size.xfrd.mem=2000
+size.config.disk=0
+size.config.mem=1000
+num.type.A=40
+num.type.TYPE65534=2
+num.opcode.QUERY=42
+num.class.IN=42
+num.rcode.NOERROR=41
+num.rcode.NXDOMAIN=1
+num.edns=10
+num.ednserr=0
+num.udp=38
+num.udp6=2
+num.tcp=1
+num.tcp6=1
+num.tls=0
+num.tls6=0
+num.answer_wo_aa=0
+num.rxerr=1
+num.txerr=2
+num.raxfr=3
+num.rixfr=4
+num.truncated=5
+num.dropped=6
+zone.master=2
+zone.slave=1
+zonestat.example.num.queries=7`, "\n")),
+			},
+			want: &Stats{
+				ServerQueries: []uint64{12, 30},
+				Queries:       42,
+				TimeBoot:      time.Hour + 500*time.Millisecond,
+				TimeElapsed:   time.Minute + 250*time.Microsecond,
+				Memory: MemoryStats{
+					DBMem:     5000,
+					XfrdMem:   2000,
+					ConfigMem: 1000,
+				},
+				QueryTypes: map[string]uint64{"A": 40, "TYPE65534": 2},
+				Opcodes:    map[string]uint64{"QUERY": 42},
+				Classes:    map[string]uint64{"IN": 42},
+				Rcodes:     map[string]uint64{"NOERROR": 41, "NXDOMAIN": 1},
+				Transport: TransportStats{
+					UDP:  38,
+					UDP6: 2,
+					TCP:  1,
+					TCP6: 1,
+				},
+				EDNS:      10,
+				RxErr:     1,
+				TxErr:     2,
+				RAXFR:     3,
+				RIXFR:     4,
+				Truncated: 5,
+				Dropped:   6,
+				Zones: ZoneCountStats{
+					Primary:   2,
+					Secondary: 1,
+				},
+				Other: map[string]string{"zonestat.example.num.queries": "7"},
+			},
+			wantErr: false,
+		},
+		{
+			name: "malformed counter",
+			args: args{
+				NewStaticReply([]string{"num.queries=-1"}),
+			},
+			want:    nil,
+			wantErr: true,
+		},
+		{
+			name: "malformed line",
+			args: args{
+				NewStaticReply([]string{"num.queries"}),
+			},
+			want:    nil,
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parseStatsReply(tt.args.c)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("parseStatsReply() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("parseStatsReply() got = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
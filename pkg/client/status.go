@@ -0,0 +1,192 @@
+package client
+
+import (
+	"fmt"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// ServerStatus is the status of the NSD daemon
+type ServerStatus struct {
+	Version   Version
+	Verbosity int
+	// Ratelimit is the configured response rate limit, nil if NSD was built without ratelimit support
+	Ratelimit *int
+	// Other holds keys not parsed into fields
+	Other map[string]string
+}
+
+// Version is a NSD release version, e.g. 4.11.0
+type Version struct {
+	Major int
+	Minor int
+	Patch int
+	// Suffix is any text following the version number, e.g. "rc1", which is ordered before the release
+	Suffix string
+}
+
+var versionRegex = regexp.MustCompile(`^(?P<major>\d+)\.(?P<minor>\d+)(?:\.(?P<patch>\d+))?(?P<suffix>.*)$`)
+
+// ParseVersion parses a NSD version as printed by the status command
+func ParseVersion(s string) (Version, error) {
+	match := versionRegex.FindStringSubmatch(s)
+	if match == nil {
+		return Version{}, fmt.Errorf("invalid version: %q", s)
+	}
+	var v Version
+	var err error
+	if v.Major, err = strconv.Atoi(match[versionRegex.SubexpIndex("major")]); err != nil {
+		return Version{}, fmt.Errorf("invalid version: %q", s)
+	}
+	if v.Minor, err = strconv.Atoi(match[versionRegex.SubexpIndex("minor")]); err != nil {
+		return Version{}, fmt.Errorf("invalid version: %q", s)
+	}
+	if patch := match[versionRegex.SubexpIndex("patch")]; patch != "" {
+		if v.Patch, err = strconv.Atoi(patch); err != nil {
+			return Version{}, fmt.Errorf("invalid version: %q", s)
+		}
+	}
+	v.Suffix = strings.TrimLeft(match[versionRegex.SubexpIndex("suffix")], "-_")
+	return v, nil
+}
+
+func (v Version) String() string {
+	s := fmt.Sprintf("%d.%d.%d", v.Major, v.Minor, v.Patch)
+	if v.Suffix != "" {
+		s += "-" + v.Suffix
+	}
+	return s
+}
+
+// Compare returns -1, 0 or +1 depending on whether v is older, the same or newer than other
+func (v Version) Compare(other Version) int {
+	for _, d := range [...]int{v.Major - other.Major, v.Minor - other.Minor, v.Patch - other.Patch} {
+		if d < 0 {
+			return -1
+		} else if d > 0 {
+			return 1
+		}
+	}
+	// Release candidates and other pre-releases come before the release
+	switch {
+	case v.Suffix == other.Suffix:
+		return 0
+	case v.Suffix == "":
+		return 1
+	case other.Suffix == "":
+		return -1
+	default:
+		return strings.Compare(v.Suffix, other.Suffix)
+	}
+}
+
+// AtLeast reports whether v is the same or newer than the given release
+func (v Version) AtLeast(major int, minor int, patch int) bool {
+	return v.Compare(Version{Major: major, Minor: minor, Patch: patch}) >= 0
+}
+
+// commandMinVersion is the first release supporting each control command, according to NSD's doc/ChangeLog
+var commandMinVersion = map[string]Version{
+	cmdActivateCookieSecret: {Major: 4, Minor: 8},
+	cmdAddCookieSecret:      {Major: 4, Minor: 8},
+	cmdAddTsig:              {Major: 4, Minor: 2},
+	cmdAddZone:              {Major: 4},
+	cmdAddZones:             {Major: 4},
+	cmdAssociateTsig:        {Major: 4, Minor: 2},
+	cmdChangeZone:           {Major: 4, Minor: 3},
+	cmdDeleteTsig:           {Major: 4, Minor: 2},
+	cmdDelZone:              {Major: 4},
+	cmdDelZones:             {Major: 4},
+	cmdDropCookieSecret:     {Major: 4, Minor: 8},
+	cmdForceTransfer:        {Major: 4},
+	cmdLogReopen:            {Major: 4},
+	cmdNotify:               {Major: 4},
+	cmdPrintCookieSecrets:   {Major: 4, Minor: 8},
+	cmdPrintTsig:            {Major: 4, Minor: 2},
+	cmdReconfig:             {Major: 4},
+	cmdReload:               {Major: 4},
+	cmdRepattern:            {Major: 4},
+	cmdServerPID:            {Major: 4},
+	cmdStats:                {Major: 4},
+	cmdStatsNoReset:         {Major: 4},
+	cmdStatus:               {Major: 4},
+	cmdStop:                 {Major: 4},
+	cmdTransfer:             {Major: 4},
+	cmdUpdateTsig:           {Major: 4, Minor: 2},
+	cmdVerbosity:            {Major: 4},
+	cmdWrite:                {Major: 4},
+	cmdZoneStatus:           {Major: 4},
+}
+
+// SupportsCommand reports whether the server version supports the control command, e.g. "add_cookie_secret"
+func (v Version) SupportsCommand(cmd string) bool {
+	minVersion, ok := commandMinVersion[cmd]
+	return ok && v.Compare(minVersion) >= 0
+}
+
+// SupportedCommands lists the control commands supported by the server version, sorted by name
+func (v Version) SupportedCommands() []string {
+	var cmds []string
+	for cmd := range commandMinVersion {
+		if v.SupportsCommand(cmd) {
+			cmds = append(cmds, cmd)
+		}
+	}
+	slices.Sort(cmds)
+	return cmds
+}
+
+// SupportsCommand reports whether the server supports the control command, see Version.SupportsCommand
+func (s *ServerStatus) SupportsCommand(cmd string) bool {
+	return s.Version.SupportsCommand(cmd)
+}
+
+func parseStatusReply(c replyReader) (*ServerStatus, error) {
+	reply, err := c.readReply()
+	if err != nil {
+		return nil, err
+	}
+
+	status := &ServerStatus{
+		Other: make(map[string]string),
+	}
+	hasVersion := false
+	for _, line := range reply {
+		if strings.HasPrefix(line, replyError) {
+			return nil, newServerError(line)
+		}
+
+		match := commonKeyValueRegex.FindStringSubmatch(line)
+		if match == nil {
+			return nil, unexpectedReply(line)
+		}
+
+		key := match[commonKeyValueRegex.SubexpIndex("key")]
+		value := match[commonKeyValueRegex.SubexpIndex("value")]
+		switch key {
+		case "version":
+			if status.Version, err = ParseVersion(value); err != nil {
+				return nil, malformedReply(key, err, line)
+			}
+			hasVersion = true
+		case "verbosity":
+			if status.Verbosity, err = strconv.Atoi(value); err != nil {
+				return nil, malformedReply(key, err, line)
+			}
+		case "ratelimit":
+			ratelimit, err := strconv.Atoi(value)
+			if err != nil {
+				return nil, malformedReply(key, err, line)
+			}
+			status.Ratelimit = &ratelimit
+		default:
+			status.Other[key] = value
+		}
+	}
+	if !hasVersion {
+		return nil, malformedReply("expected reply to contain version", nil, reply...)
+	}
+	return status, nil
+}
@@ -0,0 +1,114 @@
+package client
+
+import (
+	"reflect"
+	"testing"
+)
+
+func Test_parseStatusReply(t *testing.T) {
+	ratelimit := 200
+	type args struct {
+		c replyReader
+	}
+	tests := []struct {
+		name    string
+		args    args
+		want    *ServerStatus
+		wantErr bool
+	}{
+		{
+			name: "with ratelimit",
+			args: args{
+				NewStaticReply([]string{
+					"version: 4.11.0",
+					"verbosity: 1",
+					"ratelimit: 200",
+				}),
+			},
+			want: &ServerStatus{
+				Version:   Version{Major: 4, Minor: 11, Patch: 0},
+				Verbosity: 1,
+				Ratelimit: &ratelimit,
+				Other:     map[string]string{},
+			},
+			wantErr: false,
+		},
+		{
+			name: "without ratelimit",
+			args: args{
+				NewStaticReply([]string{
+					"version: 4.3.5",
+					"verbosity: 2",
+				}),
+			},
+			want: &ServerStatus{
+				Version:   Version{Major: 4, Minor: 3, Patch: 5},
+				Verbosity: 2,
+				Other:     map[string]string{},
+			},
+			wantErr: false,
+		},
+		{
+			name: "missing version",
+			args: args{
+				NewStaticReply([]string{"verbosity: 2"}),
+			},
+			want:    nil,
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parseStatusReply(tt.args.c)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("parseStatusReply() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("parseStatusReply() got = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestVersion_Compare(t *testing.T) {
+	tests := []struct {
+		a    string
+		b    string
+		want int
+	}{
+		{a: "4.11.0", b: "4.11.0", want: 0},
+		{a: "4.11.0", b: "4.3.5", want: 1},
+		{a: "4.3.5", b: "4.11.0", want: -1},
+		{a: "4.11.0rc1", b: "4.11.0", want: -1},
+		{a: "4.11", b: "4.11.0", want: 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.a+" "+tt.b, func(t *testing.T) {
+			a, err := ParseVersion(tt.a)
+			if err != nil {
+				t.Fatal(err)
+			}
+			b, err := ParseVersion(tt.b)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := a.Compare(b); got != tt.want {
+				t.Errorf("Compare() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestVersion_SupportsCommand(t *testing.T) {
+	old := Version{Major: 4, Minor: 3, Patch: 5}
+	if old.SupportsCommand(cmdAddCookieSecret) {
+		t.Errorf("SupportsCommand(%s) = true for %s", cmdAddCookieSecret, old)
+	}
+	if !old.SupportsCommand(cmdZoneStatus) {
+		t.Errorf("SupportsCommand(%s) = false for %s", cmdZoneStatus, old)
+	}
+	if old.SupportsCommand("no_such_command") {
+		t.Errorf("SupportsCommand(no_such_command) = true")
+	}
+}
@@ -0,0 +1,92 @@
+package client
+
+import (
+	"context"
+	"iter"
+)
+
+// The Seq methods stream a reply, parsing entries as they are read from the connection instead of collecting the
+// whole reply first. An error ends the sequence and is yielded last with a zero value.
+// The connection is held until the loop ends, so the loop body must not run commands on a single-connection client.
+// The client's timeout covers the whole iteration, streamed commands are never retried.
+
+// ZoneStatusesSeq is like ZoneStatusesContext but yields every zone as soon as its status has been read
+func (c *Client) ZoneStatusesSeq(ctx context.Context, filters ...ZoneStatusFilter) iter.Seq2[ZoneStatus, error] {
+	// NSD handler: https://github.com/NLnetLabs/nsd/blob/NSD_4_11_0_REL/remote.c#L1033
+	return streamSeq(ctx, c, cmdZoneStatus, func(lines iter.Seq2[string, error], yield func(ZoneStatus) bool) error {
+		return zoneStatusSeq(lines, filters, yield)
+	})
+}
+
+// StatsSeq is like StatsContext but yields the counters as they are read, the counters are reset
+func (c *Client) StatsSeq(ctx context.Context) iter.Seq2[StatsEntry, error] {
+	return streamSeq(ctx, c, cmdStats, statsSeq)
+}
+
+// StatsNoResetSeq is like StatsNoResetContext but yields the counters as they are read
+func (c *Client) StatsNoResetSeq(ctx context.Context) iter.Seq2[StatsEntry, error] {
+	return streamSeq(ctx, c, cmdStatsNoReset, statsSeq)
+}
+
+// TSigsSeq is like GetTSigsContext but yields the keys as they are read
+func (c *Client) TSigsSeq(ctx context.Context) iter.Seq2[TsigKey, error] {
+	return streamSeq(ctx, c, cmdPrintTsig, tsigKeySeq)
+}
+
+// streamSeq runs cmd when the sequence is iterated, parse yields the entries from the reply lines
+func streamSeq[T any](ctx context.Context, c *Client, cmd string, parse func(lines iter.Seq2[string, error], yield func(T) bool) error) iter.Seq2[T, error] {
+	return func(yield func(T, error) bool) {
+		stopped := false
+		err := c.stream(ctx, cmd, func(lines iter.Seq2[string, error]) error {
+			return parse(lines, func(v T) bool {
+				stopped = !yield(v, nil)
+				return !stopped
+			})
+		})
+		if err != nil && !stopped {
+			var zero T
+			yield(zero, err)
+		}
+	}
+}
+
+// stream sends cmd and passes the reply lines to parse as they are read
+func (c *Client) stream(ctx context.Context, cmd string, parse func(lines iter.Seq2[string, error]) error) (err error) {
+	c, err = c.begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer c.end(ctx, &err)
+
+	if err := c.sendCmd(cmd); err != nil {
+		return err
+	}
+	return parse(c.replyLines())
+}
+
+// replyLines yields the lines of a reply as they are read, until the blank line or EOF ending the reply
+func (c *Client) replyLines() iter.Seq2[string, error] {
+	return func(yield func(string, error) bool) {
+		for {
+			line, err := c.readLine()
+			if err != nil {
+				yield("", err)
+				return
+			}
+			if line == "" || !yield(line, nil) {
+				return
+			}
+		}
+	}
+}
+
+// replyLinesOf yields the lines of a reply that has already been read
+func replyLinesOf(reply []string) iter.Seq2[string, error] {
+	return func(yield func(string, error) bool) {
+		for _, line := range reply {
+			if !yield(line, nil) {
+				return
+			}
+		}
+	}
+}
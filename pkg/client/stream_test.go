@@ -0,0 +1,199 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"runtime"
+	"testing"
+)
+
+// generatedReplyConn replies with lines generated on demand, so large replies don't take memory in the test
+type generatedReplyConn struct {
+	lines int
+	line  func(i int) string
+	next  int
+	buf   []byte
+}
+
+func (g *generatedReplyConn) Read(p []byte) (int, error) {
+	if len(g.buf) == 0 {
+		if g.next >= g.lines {
+			return 0, io.EOF
+		}
+		g.buf = append(g.buf[:0], g.line(g.next)...)
+		g.next++
+	}
+	n := copy(p, g.buf)
+	g.buf = g.buf[n:]
+	return n, nil
+}
+
+func (g *generatedReplyConn) Write(p []byte) (int, error) {
+	return len(p), nil
+}
+
+func (g *generatedReplyConn) Close() error {
+	return nil
+}
+
+func newGeneratedClient(tb testing.TB, lines int, line func(i int) string) *Client {
+	tb.Helper()
+	c, err := newClient(context.Background(), &generatedReplyConn{lines: lines, line: line})
+	if err != nil {
+		tb.Fatal(err)
+	}
+	return c
+}
+
+func zoneStatusLines(i int) string {
+	return fmt.Sprintf("zone:\texample%d.com\n\tstate: ok\n\tserved-serial: \"2024010100 since 2024-01-01T00:00:00\"\n\tcommit-serial: \"2024010100 since 2024-01-01T00:00:00\"\n", i)
+}
+
+func TestClient_ZoneStatusesSeq(t *testing.T) {
+	c := newGeneratedClient(t, 1000, zoneStatusLines)
+	n := 0
+	for status, err := range c.ZoneStatusesSeq(context.Background()) {
+		if err != nil {
+			t.Fatal(err)
+		}
+		if want := fmt.Sprintf("example%d.com", n); status.Zone != want || status.State != ZoneStateOK {
+			t.Fatalf("ZoneStatusesSeq() zone %d = %s %s, want %s ok", n, status.Zone, status.State, want)
+		}
+		n++
+	}
+	if n != 1000 {
+		t.Errorf("ZoneStatusesSeq() yielded %d zones, want 1000", n)
+	}
+
+	// Stopping early doesn't read the rest of the reply
+	conn := &generatedReplyConn{lines: 1000, line: zoneStatusLines}
+	c, err := newClient(context.Background(), conn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for range c.ZoneStatusesSeq(context.Background()) {
+		break
+	}
+	if conn.next >= 1000 {
+		t.Errorf("ZoneStatusesSeq() read %d zones after break", conn.next)
+	}
+}
+
+func TestClient_ZoneStatusesSeq_error(t *testing.T) {
+	c := newGeneratedClient(t, 3, func(i int) string {
+		if i == 2 {
+			return "error zone example.net not configured\n"
+		}
+		return zoneStatusLines(i)
+	})
+	var zones []string
+	var gotErr error
+	for status, err := range c.ZoneStatusesSeq(context.Background()) {
+		if err != nil {
+			gotErr = err
+			continue
+		}
+		zones = append(zones, status.Zone)
+	}
+	if len(zones) != 1 || !errors.Is(gotErr, ErrZoneNotFound) {
+		t.Errorf("ZoneStatusesSeq() = %v, %v", zones, gotErr)
+	}
+}
+
+func TestClient_StatsSeq(t *testing.T) {
+	c := newGeneratedClient(t, 3, func(i int) string {
+		return fmt.Sprintf("server%d.queries=%d\n", i, i*10)
+	})
+	var entries []StatsEntry
+	for entry, err := range c.StatsSeq(context.Background()) {
+		if err != nil {
+			t.Fatal(err)
+		}
+		entries = append(entries, entry)
+	}
+	if len(entries) != 3 || entries[2] != (StatsEntry{Key: "server2.queries", Value: "20"}) {
+		t.Errorf("StatsSeq() = %v", entries)
+	}
+}
+
+func TestClient_TSigsSeq(t *testing.T) {
+	c := newGeneratedClient(t, 2, func(i int) string {
+		return fmt.Sprintf("key: name: \"key%d\" secret: \"K2tf3TRjvQkVCmJF3/Z9vA==\" algorithm: hmac-sha256\n", i)
+	})
+	var names []string
+	for key, err := range c.TSigsSeq(context.Background()) {
+		if err != nil {
+			t.Fatal(err)
+		}
+		names = append(names, key.Name)
+	}
+	if len(names) != 2 || names[1] != "key1" {
+		t.Errorf("TSigsSeq() = %v", names)
+	}
+}
+
+const benchmarkZones = 200_000
+
+// heapInUse returns the bytes of live heap objects, after a garbage collection
+func heapInUse() uint64 {
+	var m runtime.MemStats
+	runtime.GC()
+	runtime.ReadMemStats(&m)
+	return m.HeapAlloc
+}
+
+// BenchmarkClient_ZoneStatuses reports the heap used by collecting a large reply, compare with BenchmarkClient_ZoneStatusesSeq
+func BenchmarkClient_ZoneStatuses(b *testing.B) {
+	b.ReportAllocs()
+	var peak uint64
+	for i := 0; i < b.N; i++ {
+		c := newGeneratedClient(b, benchmarkZones, zoneStatusLines)
+		base := heapInUse()
+		statuses, err := c.ZoneStatuses()
+		if err != nil {
+			b.Fatal(err)
+		}
+		if inUse := heapInUse(); inUse > base {
+			peak = max(peak, inUse-base)
+		}
+		runtime.KeepAlive(statuses)
+	}
+	b.ReportMetric(float64(peak), "peak-heap-B")
+}
+
+func BenchmarkClient_ZoneStatusesSeq(b *testing.B) {
+	b.ReportAllocs()
+	var peak uint64
+	for i := 0; i < b.N; i++ {
+		c := newGeneratedClient(b, benchmarkZones, zoneStatusLines)
+		base := heapInUse()
+		n := 0
+		for _, err := range c.ZoneStatusesSeq(context.Background()) {
+			if err != nil {
+				b.Fatal(err)
+			}
+			if n++; n%(benchmarkZones/10) == 0 {
+				if inUse := heapInUse(); inUse > base {
+					peak = max(peak, inUse-base)
+				}
+			}
+		}
+	}
+	b.ReportMetric(float64(peak), "peak-heap-B")
+}
+
+func BenchmarkClient_StatsSeq(b *testing.B) {
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		c := newGeneratedClient(b, maxStatsServers, func(i int) string {
+			return fmt.Sprintf("server%d.queries=%d\n", i, i)
+		})
+		for _, err := range c.StatsSeq(context.Background()) {
+			if err != nil {
+				b.Fatal(err)
+			}
+		}
+	}
+}
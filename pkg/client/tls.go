@@ -1,42 +1,61 @@
 package client
 
 import (
+	"context"
 	"crypto/tls"
 	"crypto/x509"
 	"net"
 )
 
 func NewSimpleTLSClient(addr net.Addr, serverCA *x509.CertPool, clientCert tls.Certificate) (*Client, error) {
-	tlsConfig := &tls.Config{
-		RootCAs:      serverCA,
-		Certificates: []tls.Certificate{clientCert},
-	}
-	conn, err := tls.Dial(addr.Network(), addr.String(), tlsConfig)
+	return NewSimpleTLSClientContext(context.Background(), addr, serverCA, clientCert)
+}
+
+// NewSimpleTLSClientContext is like NewSimpleTLSClient but uses ctx for the deadline and cancellation of connecting
+func NewSimpleTLSClientContext(ctx context.Context, addr net.Addr, serverCA *x509.CertPool, clientCert tls.Certificate) (*Client, error) {
+	conn, err := tlsDialFunc(addr, simpleTLSConfig(serverCA, clientCert))(ctx)
 	if err != nil {
 		return nil, err
 	}
 
-	client := &Client{
-		socket: conn,
-	}
-	if err := client.init(); err != nil {
-		return nil, err
-	}
-	return client, err
+	return newClient(ctx, conn)
+}
+
+// NewDialingSimpleTLSClient creates a client connecting to addr for every command
+func NewDialingSimpleTLSClient(addr net.Addr, serverCA *x509.CertPool, clientCert tls.Certificate) *Client {
+	return NewDialingTLSClient(addr, simpleTLSConfig(serverCA, clientCert))
 }
 
 //goland:noinspection GoUnusedExportedFunction
 func NewTLSClient(addr net.TCPAddr, tlsConfig *tls.Config) (*Client, error) {
-	conn, err := tls.Dial(addr.Network(), addr.String(), tlsConfig)
+	return NewTLSClientContext(context.Background(), addr, tlsConfig)
+}
+
+// NewTLSClientContext is like NewTLSClient but uses ctx for the deadline and cancellation of connecting
+func NewTLSClientContext(ctx context.Context, addr net.TCPAddr, tlsConfig *tls.Config) (*Client, error) {
+	conn, err := tlsDialFunc(&addr, tlsConfig)(ctx)
 	if err != nil {
 		return nil, err
 	}
 
-	client := &Client{
-		socket: conn,
+	return newClient(ctx, conn)
+}
+
+// NewDialingTLSClient creates a client connecting to addr for every command
+func NewDialingTLSClient(addr net.Addr, tlsConfig *tls.Config) *Client {
+	return &Client{
+		dial:    tlsDialFunc(addr, tlsConfig),
+		timeout: DefaultTimeout,
 	}
-	if err := client.init(); err != nil {
-		return nil, err
+}
+
+func simpleTLSConfig(serverCA *x509.CertPool, clientCert tls.Certificate) *tls.Config {
+	return &tls.Config{
+		RootCAs:      serverCA,
+		Certificates: []tls.Certificate{clientCert},
 	}
-	return client, err
+}
+
+func tlsDialFunc(addr net.Addr, tlsConfig *tls.Config) dialFunc {
+	return TLSDialer(addr, tlsConfig).Dial
 }
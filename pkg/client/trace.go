@@ -0,0 +1,135 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// redacted replaces secrets in logged lines
+const redacted = "[REDACTED]"
+
+// SetLogger sets the logger tracing commands, nil disables logging.
+// Every command is logged with its duration and result, failed commands at warn level.
+// At debug level the lines sent and received are logged too, with TSIG and cookie secrets redacted.
+// It must not be called concurrently with commands.
+func (c *Client) SetLogger(logger *slog.Logger) {
+	c.logger = logger
+}
+
+// secretArgs is the position of the secret in the arguments of commands taking one
+var secretArgs = map[string]int{
+	cmdAddTsig:         1,
+	cmdUpdateTsig:      1,
+	cmdAddCookieSecret: 0,
+}
+
+// redactCmd masks the secret arguments of a command line
+func redactCmd(line string) string {
+	fields := strings.Fields(line)
+	if len(fields) == 0 {
+		return line
+	}
+	if i, ok := secretArgs[fields[0]]; ok && len(fields) > i+1 {
+		fields[i+1] = redacted
+		return strings.Join(fields, " ")
+	}
+	return line
+}
+
+var (
+	// e.g. key: name: "example" secret: "K2tf3TRjvQkVCmJF3/Z9vA==" algorithm: hmac-sha256
+	tsigSecretRegex = regexp.MustCompile(`secret: "[^"]*"`)
+	// e.g. error: the secret: K2tf3TRjvQkVCmJF3/Z9vA= is not in base64 format
+	echoedSecretRegex = regexp.MustCompile(`(secret: )[^"\s]\S*`)
+	// e.g. active : cd0636b6a5f8b9b1004b2450155ffca1, also within error messages quoting the reply
+	cookieSecretRegex = regexp.MustCompile(`\b((?:active|staging)\s*:\s*)[^\s\]"]+`)
+)
+
+// redactReplyLine masks TSIG and cookie secrets in a reply line or an error message
+func redactReplyLine(line string) string {
+	line = tsigSecretRegex.ReplaceAllLiteralString(line, `secret: "`+redacted+`"`)
+	line = echoedSecretRegex.ReplaceAllString(line, "${1}"+redacted)
+	return cookieSecretRegex.ReplaceAllString(line, "${1}"+redacted)
+}
+
+// traceSend records the command sent on a per-command client, for the log written by traceEnd
+func (c *Client) traceSend(line string) {
+	if c.logger == nil && c.observer == nil {
+		return
+	}
+	c.traceCmd = redactCmd(line)
+	if c.logger != nil {
+		c.logger.Debug("nsd control send", slog.String("line", c.traceCmd))
+	}
+}
+
+// traceReceive logs a reply line
+func (c *Client) traceReceive(line string) {
+	if c.logger == nil {
+		return
+	}
+	c.logger.Debug("nsd control receive", slog.String("line", redactReplyLine(line)))
+}
+
+// traceEnd logs and observes the command once it is done, see end
+func (c *Client) traceEnd(ctx context.Context, err error) {
+	if c.traceCmd == "" {
+		return
+	}
+	duration := time.Since(c.traceStart)
+	result := resultClass(err)
+	if c.logger != nil {
+		level := slog.LevelInfo
+		attrs := []slog.Attr{
+			slog.String("command", c.traceCmd),
+			slog.Duration("duration", duration),
+			slog.String("result", result),
+		}
+		if err != nil {
+			level = slog.LevelWarn
+			attrs = append(attrs, slog.String("error", redactReplyLine(err.Error())))
+		}
+		c.logger.LogAttrs(ctx, level, "nsd control command", attrs...)
+	}
+	if c.observer != nil {
+		event := newCommandEvent(c.traceCmd, c.socket, c.traceStart, duration, err)
+		c.observer.ObserveCommand(ctx, event)
+	}
+	c.traceCmd = ""
+}
+
+// traceConnectError logs and observes a command that failed to connect
+func (c *Client) traceConnectError(ctx context.Context, start time.Time, err error) {
+	if c.logger != nil {
+		c.logger.LogAttrs(ctx, slog.LevelWarn, "nsd control connect", slog.String("result", resultClass(err)), slog.Any("error", err))
+	}
+	if c.observer != nil {
+		c.observer.ObserveCommand(ctx, newCommandEvent("", nil, start, time.Since(start), err))
+	}
+}
+
+// resultClass classifies the outcome of a command for logs and metrics
+func resultClass(err error) string {
+	var serverErr *ServerError
+	var protocolErr *ProtocolError
+	switch {
+	case err == nil:
+		return "ok"
+	case errors.As(err, &serverErr):
+		return "server_error"
+	case errors.As(err, &protocolErr):
+		return "protocol_error"
+	case errors.Is(err, context.Canceled):
+		return "canceled"
+	case errors.Is(err, context.DeadlineExceeded):
+		return "timeout"
+	case IsConnectionError(err):
+		return "connection_error"
+	default:
+		return "error"
+	}
+}
@@ -0,0 +1,107 @@
+package client
+
+import (
+	"bytes"
+	"context"
+	"log/slog"
+	"strings"
+	"testing"
+)
+
+func Test_redactCmd(t *testing.T) {
+	tests := []struct {
+		line string
+		want string
+	}{
+		{line: "add_tsig key1 K2tf3TRjvQkVCmJF3/Z9vA== hmac-sha256", want: "add_tsig key1 [REDACTED] hmac-sha256"},
+		{line: "update_tsig key1 K2tf3TRjvQkVCmJF3/Z9vA==", want: "update_tsig key1 [REDACTED]"},
+		{line: "add_cookie_secret cd0636b6a5f8b9b1004b2450155ffca1", want: "add_cookie_secret [REDACTED]"},
+		{line: "zonestatus example.com", want: "zonestatus example.com"},
+		{line: "add_tsig", want: "add_tsig"},
+	}
+	for _, tt := range tests {
+		if got := redactCmd(tt.line); got != tt.want {
+			t.Errorf("redactCmd(%q) = %q, want %q", tt.line, got, tt.want)
+		}
+	}
+}
+
+func Test_redactReplyLine(t *testing.T) {
+	tests := []struct {
+		line string
+		want string
+	}{
+		{
+			line: `key: name: "key1" secret: "K2tf3TRjvQkVCmJF3/Z9vA==" algorithm: hmac-sha256`,
+			want: `key: name: "key1" secret: "[REDACTED]" algorithm: hmac-sha256`,
+		},
+		{line: "active : cd0636b6a5f8b9b1004b2450155ffca1", want: "active : [REDACTED]"},
+		{line: "staging: 4f08019819f6b945e03b6e91aafa0e8e", want: "staging: [REDACTED]"},
+		{line: `source : "/var/db/nsd/cookiesecrets.txt"`, want: `source : "/var/db/nsd/cookiesecrets.txt"`},
+		{line: "\tstate: ok", want: "\tstate: ok"},
+		{
+			line: "error: the secret: K2tf3TRjvQkVCmJF3/Z9vA= is not in base64 format",
+			want: "error: the secret: [REDACTED] is not in base64 format",
+		},
+		{
+			line: "unexpected reply: [active : cd0636b6a5f8b9b1004b2450155ffca1 staging: 4f08019819f6b945e03b6e91aafa0e8e]",
+			want: "unexpected reply: [active : [REDACTED] staging: [REDACTED]]",
+		},
+		{
+			line: `unexpected reply: [key: name: "key1" secret: "K2tf3TRjvQkVCmJF3/Z9vA==" algorithm: hmac-sha256]`,
+			want: `unexpected reply: [key: name: "key1" secret: "[REDACTED]" algorithm: hmac-sha256]`,
+		},
+	}
+	for _, tt := range tests {
+		if got := redactReplyLine(tt.line); got != tt.want {
+			t.Errorf("redactReplyLine(%q) = %q, want %q", tt.line, got, tt.want)
+		}
+	}
+}
+
+func TestClient_SetLogger(t *testing.T) {
+	const secret = "K2tf3TRjvQkVCmJF3/Z9vA=="
+	const invalidSecret = "K2tf3TRjvQkVCmJF3/Z9vA="
+	c := New(pipeDialer(map[string]string{
+		"add_tsig key1 " + secret + " hmac-sha256": "ok\n",
+		cmdPrintTsig: `key: name: "key1" secret: "` + secret + `" algorithm: hmac-sha256` + "\n",
+		cmdServerPID: "error unknown command\n",
+		// NSD echoes a secret it cannot decode
+		"add_tsig key2 " + invalidSecret: "error: the secret: " + invalidSecret + " is not in base64 format\n",
+	}))
+	var buf bytes.Buffer
+	c.SetLogger(slog.New(slog.NewTextHandler(&buf, &slog.HandlerOptions{Level: slog.LevelDebug})))
+
+	algorithm := TsigHmacSHA256
+	if err := c.AddTSig("key1", secret, &algorithm); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := c.GetTSigs(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := c.ServerPID(); err == nil {
+		t.Fatal("ServerPID() succeeded")
+	}
+
+	if _, err := c.Do(context.Background(), cmdAddTsig, "key2", invalidSecret); err == nil {
+		t.Fatal("Do() succeeded")
+	}
+
+	logs := buf.String()
+	if strings.Contains(logs, invalidSecret) {
+		t.Errorf("logs contain the TSIG secret:\n%s", logs)
+	}
+	for _, want := range []string{
+		`msg="nsd control command" command="add_tsig key1 [REDACTED] hmac-sha256"`,
+		`level=DEBUG msg="nsd control receive" line="key: name: \"key1\" secret: \"[REDACTED]\" algorithm: hmac-sha256"`,
+		`command=print_tsig duration=`,
+		`result=ok`,
+		`level=WARN msg="nsd control command" command=serverpid`,
+		`result=server_error`,
+		`error="server send error: error: the secret: [REDACTED] is not in base64 format"`,
+	} {
+		if !strings.Contains(logs, want) {
+			t.Errorf("logs don't contain %s:\n%s", want, logs)
+		}
+	}
+}
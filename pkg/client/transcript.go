@@ -0,0 +1,211 @@
+package client
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Transcript is a recording of control sessions, one command per session like NSD handles them.
+// Transcripts are written by Recorder and replayed by Replayer.
+//
+// The text format has a line per command, input line and reply line, each with a prefix:
+//
+//	# comment
+//	> addzones
+//	>> example.net replica
+//	@ 2024-01-01T00:00:00.123456Z 1.5ms
+//	< added: 1
+//	<! error zone exam
+//	! stalled
+//
+// Lines starting with "> " are commands without the NSDCT1 header, ">> " are the zones sent after addzones and
+// delzones, "@ " is the optional time and duration of the session and "< " are reply lines.
+// A last reply line received without its newline starts with "<! ", and a reply that wasn't ended by the server
+// closing the connection is followed by "! " and its ReplyEnd.
+// Lines that would not survive editing, e.g. with control characters or trailing spaces, are written as Go quoted
+// strings after the prefix, so lines starting with a double quote are unquoted when read.
+// Empty lines and comments are ignored.
+type Transcript struct {
+	// Comments are the comment lines at the start of the transcript, without the "# " prefix
+	Comments []string
+	Sessions []Session
+}
+
+// Session is a single command and its reply
+type Session struct {
+	// Command is the command line, e.g. "zonestatus example.com"
+	Command string
+	// Input are the lines sent after addzones and delzones
+	Input []string
+	// Time is when the session started, zero if not recorded
+	Time time.Time
+	// Duration is how long the session took, zero if not recorded
+	Duration time.Duration
+	Reply    []string
+	// Cut reports that the last reply line was received without its newline
+	Cut bool
+	// End is how the reply ended
+	End ReplyEnd
+}
+
+// ReplyEnd is how the reply of a session ended
+type ReplyEnd string
+
+const (
+	// ReplyEndClosed is a reply ended by the server closing the connection, as NSD does after every command
+	ReplyEndClosed ReplyEnd = ""
+	// ReplyEndEmptyLine is a reply ended by an empty line
+	ReplyEndEmptyLine ReplyEnd = "empty-line"
+	// ReplyEndStalled is a reply that didn't end before the client closed the connection
+	ReplyEndStalled ReplyEnd = "stalled"
+)
+
+// maxTranscriptLine bounds the lines of a transcript, above the limit of the client so oversized replies can be replayed
+const maxTranscriptLine = 16 << 20
+
+// ReadTranscriptFile reads a transcript in the text format
+func ReadTranscriptFile(path string) (*Transcript, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = f.Close() }()
+
+	t, err := ReadTranscript(f)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return t, nil
+}
+
+// ReadTranscript reads a transcript in the text format
+func ReadTranscript(r io.Reader) (*Transcript, error) {
+	t := &Transcript{}
+	var session *Session
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(nil, maxTranscriptLine)
+	for lineNo := 1; scanner.Scan(); lineNo++ {
+		line := scanner.Text()
+		if line == "" {
+			continue
+		} else if strings.HasPrefix(line, "#") {
+			if len(t.Sessions) == 0 {
+				t.Comments = append(t.Comments, strings.TrimSpace(strings.TrimPrefix(line, "#")))
+			}
+			continue
+		}
+
+		prefix, text, _ := strings.Cut(line, " ")
+		if prefix != ">" && session == nil {
+			return nil, fmt.Errorf("line %d: %q before the first command", lineNo, prefix)
+		}
+		if prefix != "@" && prefix != "!" && strings.HasPrefix(text, `"`) {
+			var err error
+			if text, err = strconv.Unquote(text); err != nil {
+				return nil, fmt.Errorf("line %d: %w", lineNo, err)
+			}
+		}
+		switch prefix {
+		case ">":
+			t.Sessions = append(t.Sessions, Session{Command: text})
+			session = &t.Sessions[len(t.Sessions)-1]
+		case ">>":
+			session.Input = append(session.Input, text)
+		case "@":
+			if err := session.parseTime(text); err != nil {
+				return nil, fmt.Errorf("line %d: %w", lineNo, err)
+			}
+		case "<", "<!":
+			if session.Cut {
+				return nil, fmt.Errorf("line %d: reply line after a cut line", lineNo)
+			}
+			session.Reply = append(session.Reply, text)
+			session.Cut = prefix == "<!"
+		case "!":
+			switch end := ReplyEnd(text); end {
+			case ReplyEndEmptyLine, ReplyEndStalled:
+				session.End = end
+			default:
+				return nil, fmt.Errorf("line %d: invalid reply end: %q", lineNo, text)
+			}
+		default:
+			return nil, fmt.Errorf("line %d: missing prefix: %q", lineNo, line)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	for _, session := range t.Sessions {
+		if session.Cut && session.End == ReplyEndEmptyLine {
+			// The empty line would have ended the cut line
+			return nil, fmt.Errorf("session %q: cut reply line before an empty line", session.Command)
+		}
+	}
+	return t, nil
+}
+
+// parseTime parses the "<time> <duration>" of a session
+func (s *Session) parseTime(text string) (err error) {
+	start, duration, _ := strings.Cut(text, " ")
+	if s.Time, err = time.Parse(time.RFC3339Nano, start); err != nil {
+		return err
+	}
+	if duration != "" {
+		if s.Duration, err = time.ParseDuration(duration); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// WriteTo writes the transcript in the text format
+func (t *Transcript) WriteTo(w io.Writer) (int64, error) {
+	var b strings.Builder
+	for _, comment := range t.Comments {
+		b.WriteString("# " + comment + "\n")
+	}
+	for _, session := range t.Sessions {
+		session.write(&b)
+	}
+	n, err := io.WriteString(w, b.String())
+	return int64(n), err
+}
+
+// write writes the session in the text format, preceded by an empty line
+func (s *Session) write(b *strings.Builder) {
+	b.WriteString("\n> " + quoteTranscriptLine(s.Command) + "\n")
+	for _, line := range s.Input {
+		b.WriteString(">> " + quoteTranscriptLine(line) + "\n")
+	}
+	if !s.Time.IsZero() {
+		b.WriteString("@ " + s.Time.Format(time.RFC3339Nano) + " " + s.Duration.String() + "\n")
+	}
+	for i, line := range s.Reply {
+		prefix := "< "
+		if s.Cut && i == len(s.Reply)-1 {
+			prefix = "<! "
+		}
+		b.WriteString(prefix + quoteTranscriptLine(line) + "\n")
+	}
+	if s.End != ReplyEndClosed {
+		b.WriteString("! " + string(s.End) + "\n")
+	}
+}
+
+// quoteTranscriptLine quotes lines that would be read back differently or are likely to be changed by editors
+func quoteTranscriptLine(line string) string {
+	needsQuote := line == "" || strings.HasPrefix(line, `"`) || !utf8.ValidString(line) ||
+		strings.TrimRight(line, " \t") != line ||
+		strings.ContainsFunc(line, func(r rune) bool { return r != '\t' && !unicode.IsPrint(r) })
+	if needsQuote {
+		return strconv.Quote(line)
+	}
+	return line
+}
@@ -0,0 +1,330 @@
+package client_test
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"net"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"nsd/pkg/client"
+	"nsd/pkg/client/nsdtest"
+)
+
+// TestTranscripts replays the control sessions captured by TestCaptureTranscript in testdata/transcripts,
+// one file per NSD release, running the same session as the capture.
+func TestTranscripts(t *testing.T) {
+	paths, err := filepath.Glob(filepath.Join("testdata", "transcripts", "nsd-*.txt"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(paths) == 0 {
+		t.Skip("no captured transcripts, see TestCaptureTranscript")
+	}
+	for _, path := range paths {
+		t.Run(strings.TrimSuffix(filepath.Base(path), ".txt"), func(t *testing.T) {
+			transcript, err := client.ReadTranscriptFile(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			replayer := client.NewReplayer(transcript)
+			c := client.New(replayer)
+			defer func() { _ = c.Close() }()
+
+			transcriptSession(t, c)
+
+			if unmatched := replayer.Unmatched(); len(unmatched) != 0 {
+				t.Errorf("commands not in transcript: %+v", unmatched)
+			}
+			if unused := replayer.Unused(); len(unused) != 0 {
+				t.Errorf("sessions not replayed: %+v", unused)
+			}
+		})
+	}
+}
+
+// TestCaptureTranscript records the session of TestTranscripts from a live server to testdata/transcripts/nsd-<version>.txt.
+// It runs when NSD_CAPTURE_ADDR is the control address of the server in test/compose.yaml, which is stopped at the end:
+//
+//	NSD_VERSION=4_3_9 docker compose -f test/compose.yaml up --build -d
+//	NSD_CAPTURE_ADDR=127.0.0.1:8952 go test ./pkg/client -run TestCaptureTranscript
+func TestCaptureTranscript(t *testing.T) {
+	addr := os.Getenv("NSD_CAPTURE_ADDR")
+	if addr == "" {
+		t.Skip("NSD_CAPTURE_ADDR not set")
+	}
+	tcpAddr, err := net.ResolveTCPAddr("tcp", addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	config := filepath.Join("..", "..", "test", "config")
+	cert, err := tls.LoadX509KeyPair(filepath.Join(config, "domain.crt"), filepath.Join(config, "domain.key"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	rootCA, err := os.ReadFile(filepath.Join(config, "rootCA.crt"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	roots := x509.NewCertPool()
+	if !roots.AppendCertsFromPEM(rootCA) {
+		t.Fatal("invalid root certificate")
+	}
+
+	var b bytes.Buffer
+	recorder := client.NewRecorder(client.TLSDialer(tcpAddr, &tls.Config{
+		Certificates: []tls.Certificate{cert},
+		RootCAs:      roots,
+		ServerName:   "localhost",
+	}), &b)
+	c := client.New(recorder)
+	status := transcriptSession(t, c)
+	_ = c.Close()
+	if err := recorder.Err(); err != nil {
+		t.Fatal(err)
+	}
+	if t.Failed() || status == nil {
+		return
+	}
+
+	transcript, err := client.ReadTranscript(&b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	transcript.Comments = []string{
+		"NSD " + status.Version.String(),
+		"Captured by TestCaptureTranscript from test/nsd with test/config/nsd.conf",
+	}
+	dir := filepath.Join("testdata", "transcripts")
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	f, err := os.Create(filepath.Join(dir, "nsd-"+status.Version.String()+".txt"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = f.Close() }()
+	if _, err := transcript.WriteTo(f); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// TestTranscriptSession records the capture session against nsdtest and replays it,
+// so the session keeps working until it is captured again from the releases.
+func TestTranscriptSession(t *testing.T) {
+	server := nsdtest.NewUNIXServer(nil)
+	defer server.Close()
+
+	var b bytes.Buffer
+	recorder := client.NewRecorder(server.Dialer(), &b)
+	c := client.New(recorder)
+	transcriptSession(t, c)
+	_ = c.Close()
+
+	transcript, err := client.ReadTranscript(&b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	replayer := client.NewReplayer(transcript)
+	c = client.New(replayer)
+	defer func() { _ = c.Close() }()
+	transcriptSession(t, c)
+	if unmatched := replayer.Unmatched(); len(unmatched) != 0 {
+		t.Errorf("commands not in transcript: %+v", unmatched)
+	}
+	if unused := replayer.Unused(); len(unused) != 0 {
+		t.Errorf("sessions not replayed: %+v", unused)
+	}
+}
+
+// transcriptSession runs every command of the client against the server configured by test/config/nsd.conf and stops it.
+// Replies may be errors from the server, e.g. for commands newer than its release, but must always be parsed.
+func transcriptSession(t *testing.T, c *client.Client) *client.ServerStatus {
+	t.Helper()
+	ctx := context.Background()
+	check := func(name string, err error) {
+		t.Helper()
+		var serverErr *client.ServerError
+		if err != nil && !errors.As(err, &serverErr) {
+			t.Errorf("%s error = %v, want nil or ServerError", name, err)
+		}
+	}
+	want := func(name string, err error, target error) {
+		t.Helper()
+		if !errors.Is(err, target) {
+			t.Errorf("%s error = %v, want %v", name, err, target)
+		}
+	}
+
+	status, err := c.Status()
+	if err != nil {
+		t.Fatalf("Status() error = %v", err)
+	}
+	supported := func(cmd string, err error) error {
+		if !status.SupportsCommand(cmd) {
+			var serverErr *client.ServerError
+			if !errors.As(err, &serverErr) {
+				t.Errorf("%s error = %v, want ServerError for a command newer than %v", cmd, err, status.Version)
+			}
+			return nil
+		}
+		return err
+	}
+
+	_, err = c.ServerPID()
+	want("ServerPID()", err, nil)
+	_, err = c.StatsNoReset()
+	want("StatsNoReset()", err, nil)
+	_, err = c.Stats()
+	want("Stats()", err, nil)
+	for _, err := range c.StatsNoResetSeq(ctx) {
+		want("StatsNoResetSeq()", err, nil)
+	}
+	for _, err := range c.StatsSeq(ctx) {
+		want("StatsSeq()", err, nil)
+	}
+
+	_, err = c.ZoneStatuses()
+	want("ZoneStatuses()", err, nil)
+	_, err = c.ZoneStatuses(client.ZoneStateFilter(client.ZoneStatePrimary))
+	want("ZoneStatuses(primary)", err, nil)
+	for _, err := range c.ZoneStatusesSeq(ctx) {
+		want("ZoneStatusesSeq()", err, nil)
+	}
+	_, err = c.ZoneStatus("example.com")
+	want("ZoneStatus(example.com)", err, nil)
+	_, err = c.ZoneStatus("example.org")
+	want("ZoneStatus(example.org)", err, nil)
+	_, err = c.ZoneStatus("example.net")
+	want("ZoneStatus(example.net)", err, client.ErrZoneNotFound)
+
+	_, err = c.GetTSigs()
+	check("GetTSigs()", supported("print_tsig", err))
+	for _, err := range c.TSigsSeq(ctx) {
+		check("TSigsSeq()", supported("print_tsig", err))
+	}
+	_, err = c.GetTSig("test")
+	check("GetTSig(test)", supported("print_tsig", err))
+	_, err = c.GetTSig("missing")
+	if status.SupportsCommand("print_tsig") {
+		want("GetTSig(missing)", err, client.ErrKeyNotFound)
+	}
+	algorithm := client.TsigHmacSHA256
+	check("AddTSig()", supported("add_tsig", c.AddTSig("key3", "K2tf3TRjvQkVCmJF3/Z9vA==", &algorithm)))
+	err = supported("add_tsig", c.AddTSig("key3", "K2tf3TRjvQkVCmJF3/Z9vA==", &algorithm))
+	if status.SupportsCommand("add_tsig") {
+		want("AddTSig(existing)", err, client.ErrKeyExists)
+	}
+	check("UpdateTSig()", supported("update_tsig", c.UpdateTSig("key3", "LhQ4ZT0rS5tETuqUK8pW5g==")))
+	check("AssocTSig()", supported("assoc_tsig", c.AssocTSig("example.org", "key3")))
+	check("DelTSig()", supported("del_tsig", c.DelTSig("key3")))
+
+	for name, op := range map[string]func([]string) (*client.ZoneOperationResult, error){
+		"Reload":        c.Reload,
+		"Write":         c.Write,
+		"Notify":        c.Notify,
+		"Transfer":      c.Transfer,
+		"ForceTransfer": c.ForceTransfer,
+	} {
+		_, err := op(nil)
+		check(name+"()", err)
+		_, err = op([]string{"example.org"})
+		check(name+"(example.org)", err)
+		_, err = op([]string{"example.net"})
+		want(name+"(example.net)", err, client.ErrZoneNotFound)
+	}
+
+	want("AddZone()", c.AddZone("example.net", "replica"), nil)
+	want("AddZone(existing)", c.AddZone("example.net", "replica"), client.ErrZoneExists)
+	want("AddZone(missing pattern)", c.AddZone("example.info", "nope"), client.ErrPatternNotFound)
+	check("ChangeZone()", supported("changezone", c.ChangeZone("example.net", "replica")))
+	want("DelZone()", c.DelZone("example.net"), nil)
+	_, err = c.AddZones([]client.ZonePattern{{Zone: "a.example", Pattern: "replica"}, {Zone: "example.com", Pattern: "replica"}})
+	check("AddZones()", err)
+	_, err = c.DelZones([]string{"a.example", "b.example"})
+	check("DelZones()", err)
+
+	_, err = c.GetCookieSecrets()
+	check("GetCookieSecrets()", supported("print_cookie_secrets", err))
+	err = supported("add_cookie_secret", c.AddCookieSecret("cd06"))
+	if status.SupportsCommand("add_cookie_secret") {
+		want("AddCookieSecret(invalid)", err, client.ErrInvalidCookieSecret)
+	}
+	check("AddCookieSecret()", supported("add_cookie_secret", c.AddCookieSecret("8e8a5cbd1ac2d2ee6b3e2eb1a6c1b4a7")))
+	check("ActivateCookieSecret()", supported("activate_cookie_secret", c.ActivateCookieSecret()))
+	check("DropCookieSecret()", supported("drop_cookie_secret", c.DropCookieSecret()))
+
+	_, err = c.Repattern()
+	check("Repattern()", err)
+	_, err = c.Reconfig()
+	check("Reconfig()", err)
+	want("Verbosity()", c.Verbosity(2), nil)
+	want("LogReopen()", c.LogReopen(), nil)
+	_, err = c.Do(ctx, "status")
+	want("Do(status)", err, nil)
+	want("Stop()", c.Stop(), nil)
+	return status
+}
+
+const testTranscript = `# NSD 4.11.0
+# test transcript
+
+> zonestatus example.com
+@ 2024-01-01T00:00:00.123456Z 1.5ms
+< zone:	example.com
+< 	state: primary
+
+> addzones
+>> example.net replica
+>> example.com replica
+< error zone example.com already exists
+< "added: 1\r"
+`
+
+func TestReadTranscript(t *testing.T) {
+	transcript, err := client.ReadTranscript(strings.NewReader(testTranscript))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := &client.Transcript{
+		Comments: []string{"NSD 4.11.0", "test transcript"},
+		Sessions: []client.Session{
+			{
+				Command:  "zonestatus example.com",
+				Time:     time.Date(2024, 1, 1, 0, 0, 0, 123456000, time.UTC),
+				Duration: 1500 * time.Microsecond,
+				Reply:    []string{"zone:\texample.com", "\tstate: primary"},
+			},
+			{
+				Command: "addzones",
+				Input:   []string{"example.net replica", "example.com replica"},
+				Reply:   []string{"error zone example.com already exists", "added: 1\r"},
+			},
+		},
+	}
+	if !reflect.DeepEqual(transcript, want) {
+		t.Errorf("ReadTranscript() = %+v, want %+v", transcript, want)
+	}
+
+	var b strings.Builder
+	if _, err := transcript.WriteTo(&b); err != nil {
+		t.Fatal(err)
+	}
+	if b.String() != testTranscript {
+		t.Errorf("WriteTo() = %q, want %q", b.String(), testTranscript)
+	}
+
+	for _, invalid := range []string{"< ok\n", ">> example.net\n", "> status\nok\n", "> status\n< \"ok\n", "> status\n@ yesterday\n",
+		"> status\n<! ver\n! empty-line\n", "> status\n<! ver\n< sion\n", "> status\n< ok\n! eof\n"} {
+		if _, err := client.ReadTranscript(strings.NewReader(invalid)); err == nil {
+			t.Errorf("ReadTranscript(%q) expected error", invalid)
+		}
+	}
+}
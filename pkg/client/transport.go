@@ -0,0 +1,164 @@
+package client
+
+import (
+	"context"
+	"crypto/tls"
+	"io"
+	"log/slog"
+	"net"
+	"time"
+)
+
+// Dialer opens a connection to the control interface of NSD, the transport commands are sent over.
+// A connection is used for a single command, as NSD closes it after replying.
+// Connections implementing SetDeadline, such as net.Conn, are interrupted with deadlines, others are closed.
+type Dialer interface {
+	Dial(ctx context.Context) (io.ReadWriteCloser, error)
+}
+
+// DialerFunc adapts a function to a Dialer
+type DialerFunc func(ctx context.Context) (io.ReadWriteCloser, error)
+
+func (f DialerFunc) Dial(ctx context.Context) (io.ReadWriteCloser, error) {
+	return f(ctx)
+}
+
+// NetDialer connects to a unix socket, or over TLS if TLSConfig is set
+type NetDialer struct {
+	// Network is "unix", or "tcp", "tcp4" or "tcp6" for TLS connections
+	Network string
+	Address string
+	// TLSConfig is required for TCP connections, NSD doesn't accept unencrypted control connections over TCP
+	TLSConfig *tls.Config
+	// NetDialer connects, a dialer with DefaultDialTimeout is used if nil
+	NetDialer *net.Dialer
+}
+
+// UNIXDialer returns a dialer for the unix socket at path
+func UNIXDialer(path string) *NetDialer {
+	return &NetDialer{Network: "unix", Address: path}
+}
+
+// TLSDialer returns a dialer for TLS connections to addr
+func TLSDialer(addr net.Addr, tlsConfig *tls.Config) *NetDialer {
+	return &NetDialer{Network: addr.Network(), Address: addr.String(), TLSConfig: tlsConfig}
+}
+
+func (d *NetDialer) Dial(ctx context.Context) (io.ReadWriteCloser, error) {
+	netDialer := d.NetDialer
+	if netDialer == nil {
+		netDialer = &net.Dialer{Timeout: DefaultDialTimeout}
+	}
+	if d.TLSConfig == nil {
+		return netDialer.DialContext(ctx, d.Network, d.Address)
+	}
+	dialer := tls.Dialer{
+		NetDialer: netDialer,
+		Config:    d.TLSConfig,
+	}
+	return dialer.DialContext(ctx, d.Network, d.Address)
+}
+
+// Option configures a client created by New
+type Option func(*options)
+
+type options struct {
+	timeout       time.Duration
+	dialTimeout   time.Duration
+	logger        *slog.Logger
+	tlsServerName string
+	netDialer     *net.Dialer
+	retry         *RetryPolicy
+	pool          *PoolConfig
+	observer      CommandObserver
+}
+
+// WithTimeout sets the deadline applied to commands when the context has no deadline, see SetTimeout
+func WithTimeout(timeout time.Duration) Option {
+	return func(o *options) {
+		o.timeout = timeout
+	}
+}
+
+// WithDialTimeout limits connecting to the server, including the TLS handshake
+func WithDialTimeout(timeout time.Duration) Option {
+	return func(o *options) {
+		o.dialTimeout = timeout
+	}
+}
+
+// WithLogger sets the logger tracing commands and retries, see SetLogger
+func WithLogger(logger *slog.Logger) Option {
+	return func(o *options) {
+		o.logger = logger
+	}
+}
+
+// WithTLSServerName sets the host name the server certificate is verified against, if the dialer is a NetDialer using TLS
+func WithTLSServerName(name string) Option {
+	return func(o *options) {
+		o.tlsServerName = name
+	}
+}
+
+// WithNetDialer sets the dialer used to connect, if the dialer is a NetDialer
+func WithNetDialer(dialer *net.Dialer) Option {
+	return func(o *options) {
+		o.netDialer = dialer
+	}
+}
+
+// WithRetryPolicy sets the retry policy for idempotent commands, see SetRetryPolicy
+func WithRetryPolicy(policy *RetryPolicy) Option {
+	return func(o *options) {
+		o.retry = policy
+	}
+}
+
+// WithPool runs commands concurrently on a pool of connections, see NewPooledUNIXSocketClient
+func WithPool(config PoolConfig) Option {
+	return func(o *options) {
+		o.pool = &config
+	}
+}
+
+// New creates a client connecting with dialer for every command, or keeping a pool of connections with WithPool
+func New(dialer Dialer, opts ...Option) *Client {
+	o := options{timeout: DefaultTimeout}
+	for _, opt := range opts {
+		opt(&o)
+	}
+
+	if netDialer, ok := dialer.(*NetDialer); ok {
+		// Don't change the caller's dialer
+		configured := *netDialer
+		if o.netDialer != nil {
+			configured.NetDialer = o.netDialer
+		}
+		if o.tlsServerName != "" && configured.TLSConfig != nil {
+			configured.TLSConfig = configured.TLSConfig.Clone()
+			configured.TLSConfig.ServerName = o.tlsServerName
+		}
+		dialer = &configured
+	}
+	dial := dialer.Dial
+	if o.dialTimeout > 0 {
+		dial = func(ctx context.Context) (io.ReadWriteCloser, error) {
+			ctx, cancel := context.WithTimeout(ctx, o.dialTimeout)
+			defer cancel()
+			return dialer.Dial(ctx)
+		}
+	}
+
+	var c *Client
+	if o.pool != nil {
+		c = newPooledClient(dial, *o.pool)
+	} else {
+		c = &Client{dial: dial}
+	}
+	c.timeout = o.timeout
+	c.retry = o.retry
+	c.logger = o.logger
+	c.observer = o.observer
+	return c
+}
@@ -0,0 +1,94 @@
+package client
+
+import (
+	"bufio"
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"io"
+	"net"
+	"strings"
+	"testing"
+	"time"
+)
+
+// pipeDialer connects to an in-memory server replying to a single command per connection
+func pipeDialer(replies map[string]string) DialerFunc {
+	return func(ctx context.Context) (io.ReadWriteCloser, error) {
+		clientConn, serverConn := net.Pipe()
+		go func() {
+			defer func() { _ = serverConn.Close() }()
+			scanner := bufio.NewScanner(serverConn)
+			if !scanner.Scan() {
+				return
+			}
+			cmd, _ := strings.CutPrefix(scanner.Text(), headerVersion)
+			_, _ = serverConn.Write([]byte(replies[cmd]))
+		}()
+		return clientConn, nil
+	}
+}
+
+func TestNew(t *testing.T) {
+	dialer := pipeDialer(map[string]string{cmdServerPID: "1234\n"})
+
+	c := New(dialer, WithTimeout(time.Second))
+	if c.timeout != time.Second {
+		t.Errorf("New() timeout = %v, want %v", c.timeout, time.Second)
+	}
+	for i := 0; i < 2; i++ {
+		if pid, err := c.ServerPID(); err != nil || pid != 1234 {
+			t.Fatalf("ServerPID() = %v, %v", pid, err)
+		}
+	}
+
+	pooled := New(dialer, WithPool(PoolConfig{MaxConns: 2}))
+	defer func() { _ = pooled.Close() }()
+	if pid, err := pooled.ServerPID(); err != nil || pid != 1234 {
+		t.Fatalf("ServerPID() = %v, %v", pid, err)
+	}
+	if stats := pooled.PoolStats(); stats.Dials == 0 {
+		t.Errorf("PoolStats() = %+v, want dials", stats)
+	}
+}
+
+func TestNew_dialTimeout(t *testing.T) {
+	dialer := DialerFunc(func(ctx context.Context) (io.ReadWriteCloser, error) {
+		<-ctx.Done()
+		return nil, ctx.Err()
+	})
+	c := New(dialer, WithDialTimeout(50*time.Millisecond))
+	if _, err := c.ServerPID(); err == nil {
+		t.Error("ServerPID() connected with a hanging dialer")
+	}
+}
+
+func TestNew_tlsServerName(t *testing.T) {
+	cert := writeSelfSignedCert(t, t.TempDir(), "nsd")
+	roots := x509.NewCertPool()
+	roots.AddCert(cert.Leaf)
+
+	l, err := tls.Listen("tcp", "127.0.0.1:0", &tls.Config{
+		Certificates: []tls.Certificate{cert},
+		ClientAuth:   tls.RequireAndVerifyClientCert,
+		ClientCAs:    roots,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = l.Close() })
+	serveOneCommandPerConn(t, l, map[string]string{cmdServerPID: "1234\n"})
+
+	tlsConfig := &tls.Config{RootCAs: roots, Certificates: []tls.Certificate{cert}}
+	// The certificate is issued to "nsd", not to the address
+	if _, err := New(TLSDialer(l.Addr(), tlsConfig)).ServerPID(); err == nil {
+		t.Error("ServerPID() accepted a certificate for another host")
+	}
+	c := New(TLSDialer(l.Addr(), tlsConfig), WithTLSServerName("nsd"), WithNetDialer(&net.Dialer{Timeout: time.Second}))
+	if pid, err := c.ServerPID(); err != nil || pid != 1234 {
+		t.Fatalf("ServerPID() = %v, %v", pid, err)
+	}
+	if tlsConfig.ServerName != "" {
+		t.Errorf("New() changed the caller's TLS config")
+	}
+}
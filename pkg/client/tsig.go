@@ -0,0 +1,164 @@
+package client
+
+import (
+	"encoding/base64"
+	"fmt"
+	"iter"
+	"regexp"
+	"strings"
+)
+
+// TsigAlgorithm is a HMAC algorithm supported by NSD for TSIG keys
+type TsigAlgorithm string
+
+// Algorithms supported by NSD, https://github.com/NLnetLabs/nsd/blob/NSD_4_11_0_REL/tsig.c (tsig_supported_algorithms)
+const (
+	TsigHmacMD5    TsigAlgorithm = "hmac-md5"
+	TsigHmacSHA1   TsigAlgorithm = "hmac-sha1"
+	TsigHmacSHA224 TsigAlgorithm = "hmac-sha224"
+	TsigHmacSHA256 TsigAlgorithm = "hmac-sha256"
+	TsigHmacSHA384 TsigAlgorithm = "hmac-sha384"
+	TsigHmacSHA512 TsigAlgorithm = "hmac-sha512"
+)
+
+// Valid reports whether NSD supports the algorithm
+func (a TsigAlgorithm) Valid() bool {
+	switch a {
+	case TsigHmacMD5, TsigHmacSHA1, TsigHmacSHA224, TsigHmacSHA256, TsigHmacSHA384, TsigHmacSHA512:
+		return true
+	default:
+		return false
+	}
+}
+
+// TsigKey is a TSIG key as configured in NSD
+type TsigKey struct {
+	Name      string
+	Algorithm TsigAlgorithm
+	// Secret is the base64 encoded shared secret
+	Secret string
+}
+
+// KeyInUseError is returned when deleting a TSIG key that is still used by a zone
+type KeyInUseError struct {
+	Key string
+	// Zone using the key, empty if the server did not name it
+	Zone string
+	// Message is the raw error message from the server
+	Message string
+}
+
+func (e *KeyInUseError) Error() string {
+	if e.Zone != "" {
+		return fmt.Sprintf("tsig key %s is in use by zone %s", e.Key, e.Zone)
+	}
+	return fmt.Sprintf("tsig key %s is in use", e.Key)
+}
+
+// Unwrap returns the error message as a ServerError
+func (e *KeyInUseError) Unwrap() error {
+	return newServerError(e.Message)
+}
+
+// validateTsigName rejects names that would be split into several arguments by the server
+func validateTsigName(name string) error {
+	if name == "" {
+		return fmt.Errorf("missing tsig key name")
+	} else if strings.ContainsAny(name, " \t\r\n") {
+		return fmt.Errorf("invalid tsig key name: %q", name)
+	}
+	return nil
+}
+
+// validateTsigSecret checks the secret the same way the server does before sending it
+func validateTsigSecret(secret string) error {
+	if secret == "" {
+		return fmt.Errorf("%w: empty secret", ErrInvalidTsigSecret)
+	}
+	if _, err := base64.StdEncoding.DecodeString(secret); err != nil {
+		return fmt.Errorf("%w: not in base64 format", ErrInvalidTsigSecret)
+	}
+	return nil
+}
+
+var tsigKeyRegex = regexp.MustCompile(`^key: name: "(?P<name>[^"]*)" secret: "(?P<secret>[^"]*)" algorithm: (?P<algorithm>\S+)$`)
+
+func parseTsigKeysReply(c replyReader) ([]TsigKey, error) {
+	reply, err := c.readReply()
+	if err != nil {
+		return nil, err
+	}
+
+	keys := make([]TsigKey, 0, len(reply))
+	err = tsigKeySeq(replyLinesOf(reply), func(key TsigKey) bool {
+		keys = append(keys, key)
+		return true
+	})
+	if err != nil {
+		return nil, err
+	}
+	return keys, nil
+}
+
+// tsigKeySeq parses the lines of a print_tsig reply, yielding every key
+func tsigKeySeq(lines iter.Seq2[string, error], yield func(TsigKey) bool) error {
+	for line, err := range lines {
+		if err != nil {
+			return err
+		}
+		if strings.HasPrefix(line, replyError) {
+			return tsigError(line, "", "")
+		}
+
+		match := tsigKeyRegex.FindStringSubmatch(line)
+		if match == nil {
+			return unexpectedReply(line)
+		}
+		key := TsigKey{
+			Name:      match[tsigKeyRegex.SubexpIndex("name")],
+			Secret:    match[tsigKeyRegex.SubexpIndex("secret")],
+			Algorithm: TsigAlgorithm(match[tsigKeyRegex.SubexpIndex("algorithm")]),
+		}
+		if !yield(key) {
+			return nil
+		}
+	}
+	return nil
+}
+
+// expectTsigOk is expectOk with the server's TSIG error messages translated into typed errors
+func expectTsigOk(c replyReader, key string, zone string) error {
+	reply, err := c.readReply()
+	if err != nil {
+		return err
+	}
+	if len(reply) == 0 {
+		return unexpectedReply(reply...)
+	}
+	if len(reply) == 1 && reply[0] == replyOK {
+		return nil
+	}
+	for _, line := range reply {
+		if strings.HasPrefix(line, replyError) {
+			return tsigError(line, key, zone)
+		}
+	}
+	return unexpectedReply(reply...)
+}
+
+var tsigKeyInUseRegex = regexp.MustCompile(`(?:used by|in use by) zone:? (?P<zone>\S+)`)
+
+// tsigError translates an error message from one of the TSIG commands, see serverErrorPatterns for the others
+// NSD handlers: https://github.com/NLnetLabs/nsd/blob/NSD_4_11_0_REL/remote.c#L2137
+func tsigError(line string, key string, zone string) error {
+	switch {
+	case strings.Contains(line, "in use") || strings.Contains(line, "used by"):
+		inUse := &KeyInUseError{Key: key, Zone: zone, Message: line}
+		if match := tsigKeyInUseRegex.FindStringSubmatch(line); match != nil {
+			inUse.Zone = match[tsigKeyInUseRegex.SubexpIndex("zone")]
+		}
+		return inUse
+	default:
+		return newServerError(line)
+	}
+}
@@ -0,0 +1,153 @@
+package client
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func Test_parseTsigKeysReply(t *testing.T) {
+	type args struct {
+		c replyReader
+	}
+	tests := []struct {
+		name    string
+		args    args
+		want    []TsigKey
+		wantErr error
+	}{
+		{
+			name: "all keys",
+			args: args{
+				NewStaticReply([]string{
+					`key: name: "test" secret: "5c9cfa3645f0e0036f8f886c502b1089" algorithm: hmac-sha256`,
+					`key: name: "test2" secret: "11c9b50555fd6bb75979d270993734ff" algorithm: hmac-sha512`,
+				}),
+			},
+			want: []TsigKey{
+				{Name: "test", Secret: "5c9cfa3645f0e0036f8f886c502b1089", Algorithm: TsigHmacSHA256},
+				{Name: "test2", Secret: "11c9b50555fd6bb75979d270993734ff", Algorithm: TsigHmacSHA512},
+			},
+		},
+		{
+			name: "no keys",
+			args: args{
+				NewStaticReply([]string{}),
+			},
+			want: []TsigKey{},
+		},
+		{
+			name: "no such key",
+			args: args{
+				NewStaticReply([]string{"error: no such key with name: test3"}),
+			},
+			wantErr: ErrKeyNotFound,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parseTsigKeysReply(tt.args.c)
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("parseTsigKeysReply() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if tt.wantErr == nil && !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("parseTsigKeysReply() got = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_expectTsigOk(t *testing.T) {
+	type args struct {
+		c    replyReader
+		key  string
+		zone string
+	}
+	tests := []struct {
+		name    string
+		args    args
+		wantErr error
+	}{
+		{
+			name: "ok",
+			args: args{c: NewStaticReply([]string{"ok"}), key: "test"},
+		},
+		{
+			name:    "key exists",
+			args:    args{c: NewStaticReply([]string{"error: key test already exists"}), key: "test"},
+			wantErr: ErrKeyExists,
+		},
+		{
+			name:    "bad secret",
+			args:    args{c: NewStaticReply([]string{"error: the secret: abc is not in base64 format"}), key: "test"},
+			wantErr: ErrInvalidTsigSecret,
+		},
+		{
+			name:    "bad algorithm",
+			args:    args{c: NewStaticReply([]string{"error: unsupported algorithm: hmac-foo"}), key: "test"},
+			wantErr: ErrUnsupportedAlgorithm,
+		},
+		{
+			name:    "unknown zone",
+			args:    args{c: NewStaticReply([]string{"error: zone: example.net does not exist"}), key: "test", zone: "example.net"},
+			wantErr: ErrZoneNotFound,
+		},
+		{
+			name:    "unknown key",
+			args:    args{c: NewStaticReply([]string{"error: key: test3 does not exist"}), key: "test3"},
+			wantErr: ErrKeyNotFound,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := expectTsigOk(tt.args.c, tt.args.key, tt.args.zone); !errors.Is(err, tt.wantErr) {
+				t.Errorf("expectTsigOk() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func Test_expectTsigOk_inUse(t *testing.T) {
+	err := expectTsigOk(NewStaticReply([]string{"error: key: test is in use by zone example.org"}), "test", "")
+	var inUse *KeyInUseError
+	if !errors.As(err, &inUse) {
+		t.Fatalf("expectTsigOk() error = %v, want *KeyInUseError", err)
+	}
+	if inUse.Key != "test" || inUse.Zone != "example.org" {
+		t.Errorf("expectTsigOk() got = %+v", inUse)
+	}
+}
+
+func TestClient_AssocTSig(t *testing.T) {
+	c := New(pipeDialer(map[string]string{cmdAssociateTsig + " example.com key1": "ok\n"}))
+	if err := c.AssocTSig("example.com", "key1"); err != nil {
+		t.Errorf("AssocTSig() error = %v", err)
+	}
+	// NSD would take the key name as part of the zone name
+	for _, zone := range []string{"", "example.com key2", "example.com\n"} {
+		if err := c.AssocTSig(zone, "key1"); err == nil {
+			t.Errorf("AssocTSig(%q) expected error", zone)
+		}
+	}
+}
+
+func TestClient_AddTSig(t *testing.T) {
+	const secret = "K2tf3TRjvQkVCmJF3/Z9vA=="
+	c := New(pipeDialer(map[string]string{
+		cmdAddTsig + " key1 " + secret:                  "ok\n",
+		cmdAddTsig + " key2 " + secret + " hmac-sha512": "ok\n",
+	}))
+	// The algorithm is only sent if given, so the server default applies
+	if err := c.AddTSig("key1", secret, nil); err != nil {
+		t.Errorf("AddTSig() error = %v", err)
+	}
+	algorithm := TsigHmacSHA512
+	if err := c.AddTSig("key2", secret, &algorithm); err != nil {
+		t.Errorf("AddTSig() error = %v", err)
+	}
+	algorithm = "hmac-sha3"
+	if err := c.AddTSig("key3", secret, &algorithm); !errors.Is(err, ErrUnsupportedAlgorithm) {
+		t.Errorf("AddTSig() error = %v, want %v", err, ErrUnsupportedAlgorithm)
+	}
+}
@@ -1,20 +1,31 @@
 package client
 
 import (
-	"net"
+	"context"
 )
 
 func NewUNIXSocketClient(path string) (*Client, error) {
-	conn, err := net.Dial("unix", path)
+	return NewUNIXSocketClientContext(context.Background(), path)
+}
+
+// NewUNIXSocketClientContext is like NewUNIXSocketClient but uses ctx for the deadline and cancellation of connecting
+func NewUNIXSocketClientContext(ctx context.Context, path string) (*Client, error) {
+	conn, err := unixDialFunc(path)(ctx)
 	if err != nil {
 		return nil, err
 	}
 
-	client := &Client{
-		socket: conn,
-	}
-	if err := client.init(); err != nil {
-		return nil, err
+	return newClient(ctx, conn)
+}
+
+// NewDialingUNIXSocketClient creates a client connecting to the unix socket at path for every command
+func NewDialingUNIXSocketClient(path string) *Client {
+	return &Client{
+		dial:    unixDialFunc(path),
+		timeout: DefaultTimeout,
 	}
-	return client, err
+}
+
+func unixDialFunc(path string) dialFunc {
+	return UNIXDialer(path).Dial
 }
@@ -0,0 +1,59 @@
+package client
+
+import (
+	"bufio"
+	"net"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// serveOneCommandPerConn replies to a single command per connection and then closes it, like NSD does
+func serveOneCommandPerConn(t *testing.T, l net.Listener, replies map[string]string) {
+	t.Helper()
+	go func() {
+		for {
+			conn, err := l.Accept()
+			if err != nil {
+				return
+			}
+			scanner := bufio.NewScanner(conn)
+			if scanner.Scan() {
+				cmd, _ := strings.CutPrefix(scanner.Text(), headerVersion)
+				if reply, ok := replies[cmd]; ok {
+					_, _ = conn.Write([]byte(reply))
+				} else {
+					_, _ = conn.Write([]byte("error unknown command '" + cmd + "'\n"))
+				}
+			}
+			_ = conn.Close()
+		}
+	}()
+}
+
+func TestNewDialingUNIXSocketClient(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "nsd.sock")
+	l, err := net.Listen("unix", path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = l.Close() })
+	serveOneCommandPerConn(t, l, map[string]string{
+		cmdServerPID: "1234\n",
+		cmdLogReopen: "ok\n",
+	})
+
+	c := NewDialingUNIXSocketClient(path)
+	defer func() { _ = c.Close() }()
+	for i := 0; i < 3; i++ {
+		if pid, err := c.ServerPID(); err != nil || pid != 1234 {
+			t.Fatalf("ServerPID() = %v, %v", pid, err)
+		}
+		if err := c.LogReopen(); err != nil {
+			t.Fatalf("LogReopen() error = %v", err)
+		}
+	}
+	if err := c.Verbosity(2); err == nil {
+		t.Errorf("Verbosity() expected error for unknown command")
+	}
+}
@@ -0,0 +1,182 @@
+package client
+
+import (
+	"fmt"
+	"math"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ZoneState is the state of a zone as reported by zonestatus
+type ZoneState string
+
+// States printed by NSD, https://github.com/NLnetLabs/nsd/blob/NSD_4_11_0_REL/remote.c#L1033
+const (
+	// ZoneStatePrimary is a zone served from a local zone file
+	ZoneStatePrimary ZoneState = "primary"
+	// ZoneStateOK is a secondary zone that is up to date
+	ZoneStateOK ZoneState = "ok"
+	// ZoneStateRefreshing is a secondary zone attempting to refresh from its primaries
+	ZoneStateRefreshing ZoneState = "refreshing"
+	// ZoneStateExpired is a secondary zone that could not be refreshed before it expired, it is no longer served
+	ZoneStateExpired ZoneState = "expired"
+	// ZoneStateBroken is a zone that failed to load
+	ZoneStateBroken ZoneState = "broken"
+)
+
+// Valid reports whether the state is one of the known states
+func (s ZoneState) Valid() bool {
+	switch s {
+	case ZoneStatePrimary, ZoneStateOK, ZoneStateRefreshing, ZoneStateExpired, ZoneStateBroken:
+		return true
+	default:
+		return false
+	}
+}
+
+type ZoneStatus struct {
+	Zone  string
+	State ZoneState
+	// Pattern the zone was added with, empty for zones configured in nsd.conf
+	Pattern string
+	// Catalog is the catalog zone role, e.g. "consumer (3 members)", empty if not a catalog zone
+	Catalog string
+	// CatalogMemberID is the member id of the zone in a catalog zone, empty if not a member
+	CatalogMemberID string
+	// ServedSerial is the serial of the zone currently served, nil if none
+	ServedSerial *ZoneSerial
+	// CommitSerial is the serial of the zone last committed to disk, nil if none
+	CommitSerial *ZoneSerial
+	// Wait is the time until the next action on a secondary zone, nil if not reported
+	Wait *ZoneWait
+	// Transfer describes a zone transfer in progress, nil if none
+	Transfer *ZoneTransfer
+	// Attributes holds any keys not parsed into fields
+	Attributes map[string]string
+}
+
+// ZoneSerial is a SOA serial and the time it was acquired
+type ZoneSerial struct {
+	Serial uint32
+	// Since is when the serial was acquired, zero if not reported
+	Since time.Time
+}
+
+// ZoneWait is the time NSD waits before next acting on a secondary zone
+type ZoneWait struct {
+	Duration time.Duration
+	// Reason is what is being waited for, e.g. "between attempts" or "until refresh"
+	Reason string
+}
+
+// ZoneTransfer is a zone transfer in progress
+type ZoneTransfer struct {
+	// Status is the progress of the transfer, e.g. "sent UDP" or "TCP connected"
+	Status string
+	// Address of the primary the transfer is from, empty if not reported
+	Address string
+}
+
+// setAttribute parses a zonestatus key into the matching field
+func (s *ZoneStatus) setAttribute(key string, value string) (err error) {
+	switch key {
+	case "state":
+		s.State = ZoneState(value)
+		// Older NSD versions use master for primary zones
+		if s.State == "master" {
+			s.State = ZoneStatePrimary
+		}
+	case "pattern":
+		s.Pattern = value
+	case "catalog":
+		s.Catalog = value
+	case "catalog-member-id":
+		s.CatalogMemberID = value
+	case "served-serial":
+		s.ServedSerial, err = parseZoneSerial(value)
+	case "commit-serial":
+		s.CommitSerial, err = parseZoneSerial(value)
+	case "wait":
+		s.Wait, err = parseZoneWait(value)
+	case "transfer":
+		s.Transfer = parseZoneTransfer(value)
+	default:
+		s.Attributes[key] = value
+	}
+	if err != nil {
+		return fmt.Errorf("malformed %s: %w", key, err)
+	}
+	return nil
+}
+
+// Format of the acquired time, https://github.com/NLnetLabs/nsd/blob/NSD_4_11_0_REL/remote.c#L1033
+const zoneSerialTimeLayout = "2006-01-02T15:04:05"
+
+// parseZoneSerial parses "none" or "<serial> since <time>"
+func parseZoneSerial(value string) (*ZoneSerial, error) {
+	if value == "none" {
+		return nil, nil
+	}
+	serial, since, hasSince := strings.Cut(value, " since ")
+	v, err := strconv.ParseUint(serial, 10, 32)
+	if err != nil {
+		return nil, err
+	}
+	zoneSerial := &ZoneSerial{Serial: uint32(v)}
+	if hasSince {
+		// NSD prints the time in its local timezone
+		if zoneSerial.Since, err = time.ParseInLocation(zoneSerialTimeLayout, since, time.Local); err != nil {
+			return nil, err
+		}
+	}
+	return zoneSerial, nil
+}
+
+var zoneWaitRegex = regexp.MustCompile(`^(?P<seconds>\d+) sec (?P<reason>.+)$`)
+
+// parseZoneWait parses e.g. "99 sec between attempts"
+func parseZoneWait(value string) (*ZoneWait, error) {
+	match := zoneWaitRegex.FindStringSubmatch(value)
+	if match == nil {
+		return nil, fmt.Errorf("unexpected wait: %s", value)
+	}
+	seconds, err := strconv.ParseInt(match[zoneWaitRegex.SubexpIndex("seconds")], 10, 64)
+	if err != nil {
+		return nil, err
+	} else if seconds > int64(math.MaxInt64/time.Second) {
+		return nil, fmt.Errorf("wait out of range: %s", value)
+	}
+	return &ZoneWait{
+		Duration: time.Duration(seconds) * time.Second,
+		Reason:   match[zoneWaitRegex.SubexpIndex("reason")],
+	}, nil
+}
+
+// parseZoneTransfer parses e.g. "sent UDP to 192.0.2.1"
+func parseZoneTransfer(value string) *ZoneTransfer {
+	status, address, _ := strings.Cut(value, " to ")
+	return &ZoneTransfer{
+		Status:  status,
+		Address: address,
+	}
+}
+
+// ZoneStatusFilter selects which zones are returned by Client.ZoneStatuses
+type ZoneStatusFilter func(status *ZoneStatus) bool
+
+// ZoneStateFilter selects zones in any of the given states
+func ZoneStateFilter(states ...ZoneState) ZoneStatusFilter {
+	return func(status *ZoneStatus) bool {
+		return slices.Contains(states, status.State)
+	}
+}
+
+// ZonePatternFilter selects zones configured from any of the given patterns
+func ZonePatternFilter(patterns ...string) ZoneStatusFilter {
+	return func(status *ZoneStatus) bool {
+		return status.Pattern != "" && slices.Contains(patterns, status.Pattern)
+	}
+}